@@ -4,6 +4,7 @@ import "github.com/DanielleB-R/golox/interpreter/token"
 
 var (
 	_ Expr = (*Assign)(nil)
+	_ Expr = (*BadExpr)(nil)
 	_ Expr = (*Binary)(nil)
 	_ Expr = (*Call)(nil)
 	_ Expr = (*Get)(nil)
@@ -13,6 +14,7 @@ var (
 	_ Expr = (*Set)(nil)
 	_ Expr = (*Super)(nil)
 	_ Expr = (*This)(nil)
+	_ Expr = (*Tuple)(nil)
 	_ Expr = (*Unary)(nil)
 	_ Expr = (*Variable)(nil)
 )
@@ -24,6 +26,7 @@ type Expr interface {
 
 type ExprVisitor interface {
 	VisitAssign(assign *Assign) any
+	VisitBadExpr(bad *BadExpr) any
 	VisitBinary(binary *Binary) any
 	VisitCall(call *Call) any
 	VisitGet(get *Get) any
@@ -33,6 +36,7 @@ type ExprVisitor interface {
 	VisitSet(set *Set) any
 	VisitSuper(super *Super) any
 	VisitThis(this *This) any
+	VisitTuple(tuple *Tuple) any
 	VisitUnary(unary *Unary) any
 	VisitVariable(variable *Variable) any
 }
@@ -47,6 +51,18 @@ func (a *Assign) Accept(visitor ExprVisitor) any {
 	return visitor.VisitAssign(a)
 }
 
+// Stands in for an expression that couldn't be parsed, from the token From to
+// the token To.
+type BadExpr struct {
+	From *token.Token
+	To   *token.Token
+}
+
+func (*BadExpr) expression() {}
+func (b *BadExpr) Accept(visitor ExprVisitor) any {
+	return visitor.VisitBadExpr(b)
+}
+
 type Binary struct {
 	Left     Expr
 	Operator *token.Token
@@ -80,7 +96,9 @@ func (g *Get) Accept(visitor ExprVisitor) any {
 }
 
 type Grouping struct {
+	LeftParen  *token.Token
 	Expression Expr
+	RightParen *token.Token
 }
 
 func (*Grouping) expression() {}
@@ -88,7 +106,9 @@ func (g *Grouping) Accept(visitor ExprVisitor) any {
 	return visitor.VisitGrouping(g)
 }
 
+// Token is nil for literals that don't appear in the source.
 type Literal struct {
+	Token *token.Token
 	Value any
 }
 
@@ -139,6 +159,15 @@ func (t *This) Accept(visitor ExprVisitor) any {
 	return visitor.VisitThis(t)
 }
 
+type Tuple struct {
+	Elements []Expr
+}
+
+func (*Tuple) expression() {}
+func (t *Tuple) Accept(visitor ExprVisitor) any {
+	return visitor.VisitTuple(t)
+}
+
 type Unary struct {
 	Operator *token.Token
 	Right    Expr
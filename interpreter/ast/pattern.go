@@ -0,0 +1,74 @@
+package ast
+
+import "github.com/DanielleB-R/golox/interpreter/token"
+
+var (
+	_ Pattern = (*BindingPattern)(nil)
+	_ Pattern = (*ClassPattern)(nil)
+	_ Pattern = (*ListPattern)(nil)
+	_ Pattern = (*LiteralPattern)(nil)
+	_ Pattern = (*RestPattern)(nil)
+	_ Pattern = (*TuplePattern)(nil)
+	_ Pattern = (*WildcardPattern)(nil)
+)
+
+type Pattern interface {
+	pattern()
+}
+
+// A bare name matches anything and binds it.
+type BindingPattern struct {
+	Name *token.Token
+}
+
+func (*BindingPattern) pattern() {}
+
+// Matches instances of Class or one of its subclasses, then matches each of
+// the named fields against its own pattern.
+type ClassPattern struct {
+	Class  *Variable
+	Fields []*FieldPattern
+}
+
+func (*ClassPattern) pattern() {}
+
+type FieldPattern struct {
+	Name    *token.Token
+	Pattern Pattern
+}
+
+type ListPattern struct {
+	Bracket  *token.Token
+	Elements []Pattern
+}
+
+func (*ListPattern) pattern() {}
+
+// Only allowed as the last element of a list or tuple pattern, where it
+// matches all of the remaining elements.
+type RestPattern struct {
+	Ellipsis *token.Token
+	Binding  Pattern
+}
+
+func (*RestPattern) pattern() {}
+
+type TuplePattern struct {
+	Paren    *token.Token
+	Elements []Pattern
+}
+
+func (*TuplePattern) pattern() {}
+
+type LiteralPattern struct {
+	Token *token.Token
+	Value any
+}
+
+func (*LiteralPattern) pattern() {}
+
+type WildcardPattern struct {
+	Token *token.Token
+}
+
+func (*WildcardPattern) pattern() {}
@@ -19,6 +19,10 @@ func (p *AstPrinter) VisitAssign(assign *Assign) any {
 	return p.parenthesize("=", &Variable{Name: assign.Name}, assign.Value)
 }
 
+func (p *AstPrinter) VisitBadExpr(bad *BadExpr) any {
+	return "(bad)"
+}
+
 func (p *AstPrinter) VisitBinary(binary *Binary) any {
 	return p.parenthesize(binary.Operator.Lexeme, binary.Left, binary.Right)
 }
@@ -59,6 +63,10 @@ func (p *AstPrinter) VisitThis(this *This) any {
 	return this.Keyword.Lexeme
 }
 
+func (p *AstPrinter) VisitTuple(tuple *Tuple) any {
+	return p.parenthesize("tuple", tuple.Elements...)
+}
+
 func (p *AstPrinter) VisitUnary(unary *Unary) any {
 	return p.parenthesize(unary.Operator.Lexeme, unary.Right)
 }
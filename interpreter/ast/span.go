@@ -0,0 +1,161 @@
+package ast
+
+import "github.com/DanielleB-R/golox/interpreter/token"
+
+var (
+	_ ExprVisitor = (*spanFinder)(nil)
+	_ StmtVisitor = (*stmtSpanFinder)(nil)
+)
+
+// Returns the span of source covered by an expression, from its first token
+// to its last.
+func SpanOf(expr Expr) token.Span {
+	return expr.Accept(&spanFinder{}).(token.Span)
+}
+
+type spanFinder struct{}
+
+func (s *spanFinder) VisitAssign(assign *Assign) any {
+	return assign.Name.Span().Join(SpanOf(assign.Value))
+}
+
+func (s *spanFinder) VisitBadExpr(bad *BadExpr) any {
+	return bad.From.Span().Join(bad.To.Span())
+}
+
+func (s *spanFinder) VisitBinary(binary *Binary) any {
+	return SpanOf(binary.Left).Join(SpanOf(binary.Right))
+}
+
+// The paren of a call is the closing one.
+func (s *spanFinder) VisitCall(call *Call) any {
+	return SpanOf(call.Callee).Join(call.Paren.Span())
+}
+
+func (s *spanFinder) VisitGet(get *Get) any {
+	return SpanOf(get.Object).Join(get.Name.Span())
+}
+
+func (s *spanFinder) VisitGrouping(grouping *Grouping) any {
+	return tokenSpan(grouping.LeftParen).Join(tokenSpan(grouping.RightParen)).Join(SpanOf(grouping.Expression))
+}
+
+func (s *spanFinder) VisitLiteral(literal *Literal) any {
+	return tokenSpan(literal.Token)
+}
+
+func (s *spanFinder) VisitLogical(logical *Logical) any {
+	return SpanOf(logical.Left).Join(SpanOf(logical.Right))
+}
+
+func (s *spanFinder) VisitSet(set *Set) any {
+	return SpanOf(set.Object).Join(SpanOf(set.Value))
+}
+
+func (s *spanFinder) VisitSuper(super *Super) any {
+	return super.Keyword.Span().Join(super.Method.Span())
+}
+
+func (s *spanFinder) VisitThis(this *This) any {
+	return this.Keyword.Span()
+}
+
+func (s *spanFinder) VisitTuple(tuple *Tuple) any {
+	span := token.Span{}
+	for _, element := range tuple.Elements {
+		span = span.Join(SpanOf(element))
+	}
+	return span
+}
+
+func (s *spanFinder) VisitUnary(unary *Unary) any {
+	return unary.Operator.Span().Join(SpanOf(unary.Right))
+}
+
+func (s *spanFinder) VisitVariable(variable *Variable) any {
+	return variable.Name.Span()
+}
+
+func tokenSpan(tok *token.Token) token.Span {
+	if tok == nil {
+		return token.Span{}
+	}
+	return tok.Span()
+}
+
+// Returns the span of source that best stands for a statement: its keyword or
+// name and the expression that follows, rather than the whole of its body.
+func StmtSpanOf(stmt Stmt) token.Span {
+	finder := &stmtSpanFinder{}
+	stmt.Accept(finder)
+	return finder.span
+}
+
+type stmtSpanFinder struct {
+	span token.Span
+}
+
+func (s *stmtSpanFinder) VisitBadStmt(stmt *BadStmt) {
+	s.span = stmt.From.Span().Join(stmt.To.Span())
+}
+
+// The first statement stands for the block, since the braces aren't kept.
+func (s *stmtSpanFinder) VisitBlock(stmt *Block) {
+	if len(stmt.Statements) > 0 {
+		s.span = StmtSpanOf(stmt.Statements[0])
+	}
+}
+
+func (s *stmtSpanFinder) VisitBreak(stmt *Break) {
+	s.span = stmt.Keyword.Span().Join(tokenSpan(stmt.Label))
+}
+
+func (s *stmtSpanFinder) VisitClass(stmt *Class) {
+	s.span = stmt.Name.Span()
+}
+
+func (s *stmtSpanFinder) VisitContinue(stmt *Continue) {
+	s.span = stmt.Keyword.Span().Join(tokenSpan(stmt.Label))
+}
+
+func (s *stmtSpanFinder) VisitExpressionStmt(stmt *ExpressionStmt) {
+	s.span = SpanOf(stmt.Expression)
+}
+
+func (s *stmtSpanFinder) VisitFunction(stmt *Function) {
+	s.span = stmt.Name.Span()
+}
+
+func (s *stmtSpanFinder) VisitIf(stmt *If) {
+	s.span = SpanOf(stmt.Condition)
+}
+
+func (s *stmtSpanFinder) VisitMatch(stmt *Match) {
+	s.span = stmt.Keyword.Span().Join(SpanOf(stmt.Subject))
+}
+
+func (s *stmtSpanFinder) VisitPrint(stmt *Print) {
+	s.span = SpanOf(stmt.Expression)
+}
+
+func (s *stmtSpanFinder) VisitReturn(stmt *Return) {
+	s.span = stmt.Keyword.Span()
+	if stmt.Value != nil {
+		s.span = s.span.Join(SpanOf(stmt.Value))
+	}
+}
+
+func (s *stmtSpanFinder) VisitVar(stmt *Var) {
+	s.span = stmt.Name.Span()
+	if stmt.Initializer != nil {
+		s.span = s.span.Join(SpanOf(stmt.Initializer))
+	}
+}
+
+func (s *stmtSpanFinder) VisitVarDestructure(stmt *VarDestructure) {
+	s.span = SpanOf(stmt.Initializer)
+}
+
+func (s *stmtSpanFinder) VisitWhile(stmt *While) {
+	s.span = tokenSpan(stmt.Keyword).Join(SpanOf(stmt.Condition))
+}
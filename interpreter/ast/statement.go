@@ -3,14 +3,19 @@ package ast
 import "github.com/DanielleB-R/golox/interpreter/token"
 
 var (
+	_ Stmt = (*BadStmt)(nil)
 	_ Stmt = (*Block)(nil)
+	_ Stmt = (*Break)(nil)
 	_ Stmt = (*Class)(nil)
+	_ Stmt = (*Continue)(nil)
 	_ Stmt = (*ExpressionStmt)(nil)
 	_ Stmt = (*Function)(nil)
 	_ Stmt = (*If)(nil)
+	_ Stmt = (*Match)(nil)
 	_ Stmt = (*Print)(nil)
 	_ Stmt = (*Return)(nil)
 	_ Stmt = (*Var)(nil)
+	_ Stmt = (*VarDestructure)(nil)
 	_ Stmt = (*While)(nil)
 )
 
@@ -20,17 +25,44 @@ type Stmt interface {
 }
 
 type StmtVisitor interface {
+	VisitBadStmt(stmt *BadStmt)
 	VisitBlock(stmt *Block)
+	VisitBreak(stmt *Break)
 	VisitClass(stmt *Class)
+	VisitContinue(stmt *Continue)
 	VisitExpressionStmt(stmt *ExpressionStmt)
 	VisitFunction(stmt *Function)
 	VisitIf(stmt *If)
+	VisitMatch(stmt *Match)
 	VisitPrint(stmt *Print)
 	VisitReturn(stmt *Return)
 	VisitVar(stmt *Var)
+	VisitVarDestructure(stmt *VarDestructure)
 	VisitWhile(stmt *While)
 }
 
+// Stands in for a statement that couldn't be parsed, covering the tokens from
+// From to To that were skipped over.
+type BadStmt struct {
+	From *token.Token
+	To   *token.Token
+}
+
+func (*BadStmt) statement() {}
+func (b *BadStmt) Accept(visitor StmtVisitor) {
+	visitor.VisitBadStmt(b)
+}
+
+type Break struct {
+	Keyword *token.Token
+	Label   *token.Token
+}
+
+func (*Break) statement() {}
+func (b *Break) Accept(visitor StmtVisitor) {
+	visitor.VisitBreak(b)
+}
+
 type Class struct {
 	Name       *token.Token
 	Superclass *Variable
@@ -42,6 +74,16 @@ func (c *Class) Accept(visitor StmtVisitor) {
 	visitor.VisitClass(c)
 }
 
+type Continue struct {
+	Keyword *token.Token
+	Label   *token.Token
+}
+
+func (*Continue) statement() {}
+func (c *Continue) Accept(visitor StmtVisitor) {
+	visitor.VisitContinue(c)
+}
+
 type ExpressionStmt struct {
 	Expression Expr
 }
@@ -73,6 +115,26 @@ func (i *If) Accept(visitor StmtVisitor) {
 	visitor.VisitIf(i)
 }
 
+type Match struct {
+	Keyword *token.Token
+	Subject Expr
+	Cases   []*MatchCase
+}
+
+func (*Match) statement() {}
+func (m *Match) Accept(visitor StmtVisitor) {
+	visitor.VisitMatch(m)
+}
+
+// A case matches if any one of its Patterns does and the Guard, if there is
+// one, is truthy.
+type MatchCase struct {
+	Keyword  *token.Token
+	Patterns []Pattern
+	Guard    Expr
+	Body     Stmt
+}
+
 type Print struct {
 	Expression Expr
 }
@@ -102,9 +164,25 @@ func (v *Var) Accept(visitor StmtVisitor) {
 	visitor.VisitVar(v)
 }
 
+type VarDestructure struct {
+	Pattern     Pattern
+	Initializer Expr
+}
+
+func (*VarDestructure) statement() {}
+func (v *VarDestructure) Accept(visitor StmtVisitor) {
+	visitor.VisitVarDestructure(v)
+}
+
+// Label is nil for an unlabeled loop. Keyword is the while or for keyword.
+// Increment is only set for loops desugared from a for statement, so that
+// continue still runs it.
 type While struct {
+	Keyword   *token.Token
+	Label     *token.Token
 	Condition Expr
 	Body      Stmt
+	Increment Expr
 }
 
 func (*While) statement() {}
@@ -0,0 +1,452 @@
+package interpreter
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+
+	"github.com/DanielleB-R/golox/interpreter/token"
+)
+
+var (
+	_ Object       = (*LoxInstance)(nil)
+	_ Object       = (*GoObject)(nil)
+	_ fmt.Stringer = (*GoObject)(nil)
+)
+
+// An Object has properties that Lox code can get and set with dots.
+type Object interface {
+	Get(name *token.Token) (any, error)
+	Set(name *token.Token, value any) error
+}
+
+// A GoObject exposes a Go value to Lox. The exported fields of a struct are
+// properties, as are the entries of a map with string keys, and exported
+// methods can be called. Values are converted as they pass between Go and Lox,
+// as ToLox and FromLox describe.
+type GoObject struct {
+	value reflect.Value
+}
+
+// Wraps value, which is usually a pointer to a struct. A struct that isn't
+// behind a pointer is copied, so that its fields can still be set. There is
+// nothing to wrap when value is nil or a nil pointer, so the result is nil,
+// which ToLox turns into Lox's nil.
+func NewGoObject(value any) *GoObject {
+	v := reflect.ValueOf(value)
+	if isNil(v) {
+		return nil
+	}
+	if v.Kind() == reflect.Struct {
+		pointer := reflect.New(v.Type())
+		pointer.Elem().Set(v)
+		v = pointer
+	}
+	return &GoObject{value: v}
+}
+
+// The Go value that was wrapped
+func (g *GoObject) Value() any {
+	if !g.value.IsValid() {
+		return nil
+	}
+	return g.value.Interface()
+}
+
+func (g *GoObject) String() string {
+	if isNil(g.value) {
+		return "<go nil>"
+	}
+	if stringer, ok := g.value.Interface().(fmt.Stringer); ok {
+		return stringer.String()
+	}
+	return fmt.Sprintf("<go %s>", g.value.Type())
+}
+
+func (g *GoObject) Get(name *token.Token) (any, error) {
+	if isNil(g.value) {
+		return nil, g.nilError(name)
+	}
+	if method := g.value.MethodByName(name.Lexeme); method.IsValid() {
+		return goFunction(g.value.Type().String()+"."+name.Lexeme, method), nil
+	}
+
+	target := reflect.Indirect(g.value)
+	switch target.Kind() {
+	case reflect.Struct:
+		if field, ok := target.Type().FieldByName(name.Lexeme); ok && field.IsExported() {
+			return toLox(target.FieldByIndex(field.Index)), nil
+		}
+	case reflect.Map:
+		if target.Type().Key().Kind() == reflect.String {
+			if value := target.MapIndex(reflect.ValueOf(name.Lexeme).Convert(target.Type().Key())); value.IsValid() {
+				return toLox(value), nil
+			}
+		}
+	}
+
+	return nil, &RuntimeError{
+		token:   name,
+		code:    UNDEFINED_PROPERTY_ERROR,
+		message: fmt.Sprintf("Undefined property '%s'.", name.Lexeme) + suggestion(name.Lexeme, g.propertyNames()),
+	}
+}
+
+func (g *GoObject) Set(name *token.Token, value any) error {
+	if isNil(g.value) {
+		return g.nilError(name)
+	}
+	target := reflect.Indirect(g.value)
+	switch target.Kind() {
+	case reflect.Struct:
+		field, ok := target.Type().FieldByName(name.Lexeme)
+		if !ok || !field.IsExported() {
+			return &RuntimeError{
+				token:   name,
+				code:    UNDEFINED_PROPERTY_ERROR,
+				message: fmt.Sprintf("Undefined property '%s'.", name.Lexeme) + suggestion(name.Lexeme, g.propertyNames()),
+			}
+		}
+		converted, err := FromLox(value, field.Type)
+		if err != nil {
+			return &RuntimeError{token: name, code: TYPE_ERROR, message: err.Error()}
+		}
+		target.FieldByIndex(field.Index).Set(converted)
+		return nil
+	case reflect.Map:
+		if target.Type().Key().Kind() == reflect.String && !target.IsNil() {
+			converted, err := FromLox(value, target.Type().Elem())
+			if err != nil {
+				return &RuntimeError{token: name, code: TYPE_ERROR, message: err.Error()}
+			}
+			target.SetMapIndex(reflect.ValueOf(name.Lexeme).Convert(target.Type().Key()), converted)
+			return nil
+		}
+	}
+
+	return &RuntimeError{
+		token:   name,
+		code:    TYPE_ERROR,
+		message: fmt.Sprintf("Can't set properties on %s.", g.value.Type()),
+	}
+}
+
+// Methods can't be called on a nil receiver, and there are no fields to
+// reach through one.
+func (g *GoObject) nilError(name *token.Token) *RuntimeError {
+	return &RuntimeError{
+		token:   name,
+		code:    TYPE_ERROR,
+		message: fmt.Sprintf("Can't use property '%s' of a nil Go value.", name.Lexeme),
+	}
+}
+
+func (g *GoObject) propertyNames() []string {
+	names := []string{}
+	if !g.value.IsValid() {
+		return names
+	}
+	for i := range g.value.Type().NumMethod() {
+		names = append(names, g.value.Type().Method(i).Name)
+	}
+
+	target := reflect.Indirect(g.value)
+	switch target.Kind() {
+	case reflect.Struct:
+		for _, field := range reflect.VisibleFields(target.Type()) {
+			if field.IsExported() && !field.Anonymous {
+				names = append(names, field.Name)
+			}
+		}
+	case reflect.Map:
+		if target.Type().Key().Kind() == reflect.String {
+			for _, key := range target.MapKeys() {
+				names = append(names, key.String())
+			}
+		}
+	}
+	return names
+}
+
+// Wraps a Go function as a native. A final error result is raised as a
+// RuntimeError, and when there is more than one other result they are
+// returned as a tuple.
+func goFunction(name string, fn reflect.Value) *NativeFunction {
+	fnType := fn.Type()
+	return &NativeFunction{
+		name:  name,
+		arity: fnType.NumIn(),
+		behaviour: func(interpreter *Interpreter, arguments []any) any {
+			in := []reflect.Value{}
+			for i, argument := range arguments {
+				converted, err := FromLox(argument, fnType.In(i))
+				if err != nil {
+					panic(&RuntimeError{code: TYPE_ERROR, message: err.Error(), cause: err})
+				}
+				in = append(in, converted)
+			}
+
+			out, err := callGo(fn, in)
+			if err != nil {
+				panic(&RuntimeError{code: NATIVE_ERROR, message: fmt.Sprintf("%s panicked: %s", name, err), cause: err})
+			}
+
+			if len(out) > 0 && fnType.Out(len(out)-1) == errorType {
+				if err, _ := out[len(out)-1].Interface().(error); err != nil {
+					panic(&RuntimeError{code: NATIVE_ERROR, message: err.Error(), cause: err})
+				}
+				out = out[:len(out)-1]
+			}
+
+			switch len(out) {
+			case 0:
+				return nil
+			case 1:
+				return toLox(out[0])
+			}
+			elements := []any{}
+			for _, result := range out {
+				elements = append(elements, toLox(result))
+			}
+			return NewLoxTuple(elements)
+		},
+	}
+}
+
+var errorType = reflect.TypeFor[error]()
+
+// A panic in Go code called from Lox is returned as an error, so that it
+// stops the Lox program rather than the host.
+func callGo(fn reflect.Value, in []reflect.Value) (out []reflect.Value, err error) {
+	defer func() {
+		recovered := recover()
+		if recovered == nil {
+			return
+		}
+		if err, _ = recovered.(error); err == nil {
+			err = fmt.Errorf("%v", recovered)
+		}
+	}()
+
+	if fn.Type().IsVariadic() {
+		return fn.CallSlice(in), nil
+	}
+	return fn.Call(in), nil
+}
+
+// Whether v holds nothing, either because it is the zero Value or because it
+// is a nil pointer, map, slice, function, channel or interface.
+func isNil(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Invalid:
+		return true
+	case reflect.Pointer, reflect.Interface, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan:
+		return v.IsNil()
+	}
+	return false
+}
+
+// Converts a Go value to the Lox value that stands for it. Integers become
+// int64, or *big.Int if they don't fit, and other numbers become float64.
+// Slices and arrays are copied into lists. Functions become natives, and
+// anything else that isn't already a Lox value is wrapped in a GoObject.
+func ToLox(value any) Value {
+	return toLox(reflect.ValueOf(value))
+}
+
+func toLox(v reflect.Value) Value {
+	// Including a nil *GoObject or *LoxInstance, which would otherwise pass
+	// as a Lox value
+	if v.Kind() == reflect.Pointer && v.IsNil() {
+		return nil
+	}
+	if v.IsValid() && v.CanInterface() && isLoxValue(v.Interface()) {
+		return v.Interface()
+	}
+
+	switch v.Kind() {
+	case reflect.Invalid:
+		return nil
+	case reflect.Bool:
+		return v.Bool()
+	case reflect.String:
+		return v.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if v.Uint() > math.MaxInt64 {
+			return new(big.Int).SetUint64(v.Uint())
+		}
+		return int64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil
+		}
+		elements := []any{}
+		for i := range v.Len() {
+			elements = append(elements, toLox(v.Index(i)))
+		}
+		return NewLoxList(elements)
+	case reflect.Func:
+		if v.IsNil() {
+			return nil
+		}
+		return goFunction(v.Type().String(), v)
+	case reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return toLox(v.Elem())
+	case reflect.Pointer, reflect.Map, reflect.Chan:
+		if v.IsNil() {
+			return nil
+		}
+	case reflect.Struct:
+		// Fields of a struct we already share are shared too, rather than
+		// copied
+		if v.CanAddr() {
+			return &GoObject{value: v.Addr()}
+		}
+		return NewGoObject(v.Interface())
+	}
+	return &GoObject{value: v}
+}
+
+func isLoxValue(value any) bool {
+	switch value.(type) {
+	case nil, bool, string, int64, float64, *big.Int, *Decimal,
+		*LoxInstance, *LoxList, *LoxTuple, *GoObject, Callable:
+		return true
+	}
+	return false
+}
+
+// Converts a Lox value to a Go value of type t. Numbers convert to any Go
+// number type they fit in, lists and tuples to slices and arrays, and
+// instances to maps with string keys. A GoObject converts back to the value
+// it wraps.
+func FromLox(value Value, t reflect.Type) (reflect.Value, error) {
+	if object, ok := value.(*GoObject); ok {
+		if object.value.Type().AssignableTo(t) {
+			return object.value, nil
+		}
+		if object.value.Kind() == reflect.Pointer && object.value.Elem().Type().AssignableTo(t) {
+			return object.value.Elem(), nil
+		}
+	}
+
+	if value == nil {
+		switch t.Kind() {
+		case reflect.Pointer, reflect.Interface, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan:
+			return reflect.Zero(t), nil
+		}
+		return reflect.Value{}, cantConvert(value, t)
+	}
+
+	v := reflect.ValueOf(value)
+	if v.Type().AssignableTo(t) {
+		return v, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		if b, ok := value.(bool); ok {
+			return reflect.ValueOf(b).Convert(t), nil
+		}
+	case reflect.String:
+		if s, ok := value.(string); ok {
+			return reflect.ValueOf(s).Convert(t), nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if i, ok := integerValue(value); ok && i.IsInt64() && !reflect.Zero(t).OverflowInt(i.Int64()) {
+			return reflect.ValueOf(i.Int64()).Convert(t), nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if i, ok := integerValue(value); ok && i.IsUint64() && !reflect.Zero(t).OverflowUint(i.Uint64()) {
+			return reflect.ValueOf(i.Uint64()).Convert(t), nil
+		}
+	case reflect.Float32, reflect.Float64:
+		if isNumber(value) {
+			return reflect.ValueOf(toFloat(value)).Convert(t), nil
+		}
+	case reflect.Slice, reflect.Array:
+		if elements, ok := listElements(value); ok {
+			return sequenceFromLox(value, elements, t)
+		}
+	case reflect.Map:
+		if instance, ok := value.(*LoxInstance); ok && t.Key().Kind() == reflect.String {
+			m := reflect.MakeMapWithSize(t, len(instance.fields))
+			for name, field := range instance.fields {
+				converted, err := FromLox(field, t.Elem())
+				if err != nil {
+					return reflect.Value{}, err
+				}
+				m.SetMapIndex(reflect.ValueOf(name).Convert(t.Key()), converted)
+			}
+			return m, nil
+		}
+	}
+	return reflect.Value{}, cantConvert(value, t)
+}
+
+func sequenceFromLox(value Value, elements []any, t reflect.Type) (reflect.Value, error) {
+	var sequence reflect.Value
+	if t.Kind() == reflect.Array {
+		if len(elements) != t.Len() {
+			return reflect.Value{}, fmt.Errorf("Can't convert %s with %d elements to %s.", stringify(value), len(elements), t)
+		}
+		sequence = reflect.New(t).Elem()
+	} else {
+		sequence = reflect.MakeSlice(t, len(elements), len(elements))
+	}
+
+	for i, element := range elements {
+		converted, err := FromLox(element, t.Elem())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		sequence.Index(i).Set(converted)
+	}
+	return sequence, nil
+}
+
+// Returns the value as a big integer if it is a whole number.
+func integerValue(value Value) (*big.Int, bool) {
+	switch value := value.(type) {
+	case int64:
+		return big.NewInt(value), true
+	case *big.Int:
+		return value, true
+	case *Decimal:
+		if value.IsInteger() {
+			return value.Floor(), true
+		}
+	case float64:
+		if !math.IsInf(value, 0) && value == math.Trunc(value) {
+			i, _ := big.NewFloat(value).Int(nil)
+			return i, true
+		}
+	}
+	return nil, false
+}
+
+func listElements(value Value) ([]any, bool) {
+	switch value := value.(type) {
+	case *LoxList:
+		return value.elements, true
+	case *LoxTuple:
+		return value.elements, true
+	}
+	return nil, false
+}
+
+func cantConvert(value Value, t reflect.Type) error {
+	description := stringify(value)
+	if _, ok := value.(string); ok {
+		description = fmt.Sprintf("%q", value)
+	}
+	return fmt.Errorf("Can't convert %s to %s.", description, t)
+}
@@ -0,0 +1,221 @@
+package interpreter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testCustomer struct {
+	Name  string
+	Email string
+}
+
+type testOrder struct {
+	ID       int
+	Total    float64
+	Lines    []string
+	Customer testCustomer
+	Tags     map[string]int
+	secret   string
+}
+
+func (o *testOrder) AddLine(line string, price float64) int {
+	o.Lines = append(o.Lines, line)
+	o.Total += price
+	return len(o.Lines)
+}
+
+func (o *testOrder) Split() (int, string) {
+	return o.ID, o.Customer.Name
+}
+
+func (o *testOrder) Cancel(reason string) error {
+	if reason == "" {
+		return errors.New("a reason is needed")
+	}
+	return nil
+}
+
+func (o *testOrder) Line(n int) string {
+	return o.Lines[n]
+}
+
+func evalWith(t *testing.T, name string, value any, source string) (Value, error) {
+	interpreter := NewInterpreter()
+	interpreter.DefineGlobal(name, value)
+	return interpreter.Eval(context.Background(), source)
+}
+
+func TestGoObjectFields(t *testing.T) {
+	order := &testOrder{ID: 7, Customer: testCustomer{Name: "Ada"}, Tags: map[string]int{"rush": 1}}
+
+	value, err := evalWith(t, "order", order, `
+order.Total = 12.5;
+order.Customer.Name = order.Customer.Name + " L";
+order.Tags.gift = 2;
+order.ID + order.Tags.rush;
+`)
+	require.NoError(t, err)
+	require.Equal(t, int64(8), value)
+	require.Equal(t, 12.5, order.Total)
+	require.Equal(t, "Ada L", order.Customer.Name)
+	require.Equal(t, 2, order.Tags["gift"])
+}
+
+func TestGoObjectMethods(t *testing.T) {
+	order := &testOrder{ID: 7, Customer: testCustomer{Name: "Ada"}}
+
+	value, err := evalWith(t, "order", order, `
+order.AddLine("tea", 3);
+order.AddLine("cake", 4.5);
+`)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), value)
+	require.Equal(t, []string{"tea", "cake"}, order.Lines)
+	require.Equal(t, 7.5, order.Total)
+
+	value, err = evalWith(t, "order", order, "order.Split();")
+	require.NoError(t, err)
+	require.Equal(t, NewLoxTuple([]any{int64(7), "Ada"}), value)
+
+	value, err = evalWith(t, "order", order, "order.Lines;")
+	require.NoError(t, err)
+	require.Equal(t, NewLoxList([]any{"tea", "cake"}), value)
+
+	_, err = evalWith(t, "order", order, `order.Cancel("");`)
+	require.ErrorContains(t, err, "a reason is needed")
+
+	value, err = evalWith(t, "order", order, `order.Cancel("late");`)
+	require.NoError(t, err)
+	require.Nil(t, value)
+}
+
+func TestGoObjectErrors(t *testing.T) {
+	cases := map[string]string{
+		"order.Totl;":          "Undefined property 'Totl'. Did you mean 'Total'?",
+		"order.secret;":        "Undefined property 'secret'.",
+		`order.ID = "seven";`:  `Can't convert "seven" to int.`,
+		"order.ID = 1.5;":      "Can't convert 1.5 to int.",
+		"order.AddLine(1, 2);": "Can't convert 1 to string.",
+	}
+
+	for source, message := range cases {
+		t.Run(source, func(t *testing.T) {
+			_, err := evalWith(t, "order", &testOrder{}, source)
+			var runtimeError *RuntimeError
+			require.ErrorAs(t, err, &runtimeError)
+			require.Equal(t, message, runtimeError.Message())
+		})
+	}
+}
+
+func TestGoObjectNil(t *testing.T) {
+	require.Nil(t, NewGoObject(nil))
+	require.Nil(t, NewGoObject((*testOrder)(nil)))
+
+	value, err := evalWith(t, "order", NewGoObject((*testOrder)(nil)), "order == nil;")
+	require.NoError(t, err)
+	require.Equal(t, true, value)
+
+	for _, object := range []*GoObject{{}, {value: reflect.ValueOf((*testOrder)(nil))}} {
+		require.Equal(t, "<go nil>", object.String())
+		_, err = evalWith(t, "order", object, "order.Split();")
+		var runtimeError *RuntimeError
+		require.ErrorAs(t, err, &runtimeError)
+		require.Equal(t, "Can't use property 'Split' of a nil Go value.", runtimeError.Message())
+		_, err = evalWith(t, "order", object, "order.ID = 1;")
+		require.ErrorAs(t, err, &runtimeError)
+	}
+}
+
+func TestGoPanicsBecomeRuntimeErrors(t *testing.T) {
+	_, err := evalWith(t, "order", &testOrder{}, "\norder.Line(3);")
+	var runtimeError *RuntimeError
+	require.ErrorAs(t, err, &runtimeError)
+	require.Equal(t, NATIVE_ERROR, runtimeError.Code())
+	require.Equal(t, "*interpreter.testOrder.Line panicked: runtime error: index out of range [3] with length 0", runtimeError.Message())
+	require.Equal(t, 2, runtimeError.Span().Start.Line)
+	var goError runtime.Error
+	require.ErrorAs(t, err, &goError)
+
+	_, err = evalWith(t, "id", func(o *testOrder) int { return o.ID }, "id(nil);")
+	require.ErrorAs(t, err, &runtimeError)
+	require.ErrorAs(t, err, &goError)
+
+	_, err = evalWith(t, "fail", func() { panic("not an error") }, "fail();")
+	require.ErrorAs(t, err, &runtimeError)
+	require.Equal(t, "func() panicked: not an error", runtimeError.Message())
+}
+
+func TestGoObjectCopiesStructValues(t *testing.T) {
+	customer := testCustomer{Name: "Ada"}
+	value, err := evalWith(t, "customer", customer, `customer.Name = "Grace"; customer;`)
+	require.NoError(t, err)
+	require.Equal(t, "Ada", customer.Name)
+	require.Equal(t, &testCustomer{Name: "Grace"}, value.(*GoObject).Value())
+}
+
+func TestGoFunctions(t *testing.T) {
+	interpreter := NewInterpreter()
+	interpreter.DefineGlobal("sum", func(numbers []int) int {
+		total := 0
+		for _, n := range numbers {
+			total += n
+		}
+		return total
+	})
+	interpreter.DefineGlobal("numbers", []int{1, 2, 3})
+	interpreter.DefineGlobal("greet", func(name string) string {
+		return fmt.Sprintf("hello %s", name)
+	})
+
+	value, err := interpreter.Eval(context.Background(), `sum(numbers);`)
+	require.NoError(t, err)
+	require.Equal(t, int64(6), value)
+
+	value, err = interpreter.Eval(context.Background(), `greet("you");`)
+	require.NoError(t, err)
+	require.Equal(t, "hello you", value)
+}
+
+func TestLoxValueConversions(t *testing.T) {
+	require.Equal(t, int64(3), ToLox(uint8(3)))
+	require.Equal(t, new(big.Int).SetUint64(1<<63), ToLox(uint64(1<<63)))
+	require.Equal(t, float64(float32(1.5)), ToLox(float32(1.5)))
+	require.Nil(t, ToLox((*testOrder)(nil)))
+	require.Nil(t, ToLox([]int(nil)))
+
+	cases := []struct {
+		value    Value
+		expected any
+	}{
+		{int64(3), int8(3)},
+		{int64(3), 3.0},
+		{2.0, 2},
+		{big.NewInt(5), uint(5)},
+		{"text", "text"},
+		{nil, []int(nil)},
+		{NewLoxList([]any{int64(1), 2.5}), []float64{1, 2.5}},
+		{NewLoxTuple([]any{"a", "b"}), [2]string{"a", "b"}},
+		{NewLoxList([]any{int64(1), "a"}), []any{int64(1), "a"}},
+	}
+	for _, c := range cases {
+		converted, err := FromLox(c.value, reflect.TypeOf(c.expected))
+		require.NoError(t, err)
+		require.Equal(t, c.expected, converted.Interface())
+	}
+
+	_, err := FromLox(int64(300), reflect.TypeFor[int8]())
+	require.EqualError(t, err, "Can't convert 300 to int8.")
+	_, err = FromLox(int64(-1), reflect.TypeFor[uint]())
+	require.Error(t, err)
+	_, err = FromLox(nil, reflect.TypeFor[int]())
+	require.Error(t, err)
+}
@@ -2,6 +2,9 @@ package interpreter
 
 import (
 	"fmt"
+	"math"
+	"math/big"
+	"strconv"
 	"time"
 
 	"github.com/DanielleB-R/golox/interpreter/ast"
@@ -20,10 +23,31 @@ type Callable interface {
 }
 
 type NativeFunction struct {
+	name      string
 	arity     int
 	behaviour func(*Interpreter, []any) any
 }
 
+// Wraps fn so that Lox code can call it. Its result is converted as ToLox
+// does, and an error returned by fn is raised as a RuntimeError at the call
+// site, which unwraps to the original error.
+func NewNativeFunction(name string, arity int, fn func(*Interpreter, []Value) (Value, error)) *NativeFunction {
+	return &NativeFunction{
+		name:  name,
+		arity: arity,
+		behaviour: func(interpreter *Interpreter, arguments []any) any {
+			result, err := fn(interpreter, arguments)
+			if err != nil {
+				if runtimeError, ok := err.(*RuntimeError); ok {
+					panic(runtimeError)
+				}
+				panic(&RuntimeError{code: NATIVE_ERROR, message: err.Error(), cause: err})
+			}
+			return ToLox(result)
+		},
+	}
+}
+
 func (*NativeFunction) String() string {
 	return "<native fn>"
 }
@@ -37,36 +61,127 @@ func (n *NativeFunction) Call(interpreter *Interpreter, arguments []any) any {
 }
 
 var Clock *NativeFunction = &NativeFunction{
+	name:  "clock",
 	arity: 0,
 	behaviour: func(interpreter *Interpreter, arguments []any) any {
 		return float64(time.Now().Unix())
 	},
 }
 
+var ToBigInt *NativeFunction = &NativeFunction{
+	name:  "bigint",
+	arity: 1,
+	behaviour: func(interpreter *Interpreter, arguments []any) any {
+		switch value := arguments[0].(type) {
+		case int64:
+			return big.NewInt(value)
+		case *big.Int:
+			return value
+		case *Decimal:
+			if !value.IsInteger() {
+				panic(&RuntimeError{code: TYPE_ERROR, message: fmt.Sprintf("Can't convert %s to a big integer without losing its fraction.", value)})
+			}
+			return value.Floor()
+		case float64:
+			if math.IsInf(value, 0) || math.IsNaN(value) || value != math.Trunc(value) {
+				panic(&RuntimeError{code: TYPE_ERROR, message: fmt.Sprintf("Can't convert %s to a big integer without losing its fraction.", formatFloat(value))})
+			}
+			i, _ := big.NewFloat(value).Int(nil)
+			return i
+		case string:
+			if i, ok := new(big.Int).SetString(value, 10); ok {
+				return i
+			}
+			panic(&RuntimeError{code: TYPE_ERROR, message: fmt.Sprintf("Can't convert \"%s\" to a big integer.", value)})
+		}
+		panic(&RuntimeError{code: TYPE_ERROR, message: "Argument to 'bigint' must be a number or string."})
+	},
+}
+
+// A float converts to the shortest decimal that reads back as the same float.
+var ToDecimal *NativeFunction = &NativeFunction{
+	name:  "decimal",
+	arity: 1,
+	behaviour: func(interpreter *Interpreter, arguments []any) any {
+		switch value := arguments[0].(type) {
+		case int64, *big.Int, *Decimal:
+			return promote(value, DECIMAL)
+		case float64:
+			if math.IsInf(value, 0) || math.IsNaN(value) {
+				panic(&RuntimeError{code: TYPE_ERROR, message: fmt.Sprintf("Can't convert %s to a decimal.", formatFloat(value))})
+			}
+			decimal, _ := ParseDecimal(strconv.FormatFloat(value, 'f', -1, 64))
+			return decimal
+		case string:
+			if decimal, err := ParseDecimal(value); err == nil {
+				return decimal
+			}
+			panic(&RuntimeError{code: TYPE_ERROR, message: fmt.Sprintf("Can't convert \"%s\" to a decimal.", value)})
+		}
+		panic(&RuntimeError{code: TYPE_ERROR, message: "Argument to 'decimal' must be a number or string."})
+	},
+}
+
+var ToFloat *NativeFunction = &NativeFunction{
+	name:  "float",
+	arity: 1,
+	behaviour: func(interpreter *Interpreter, arguments []any) any {
+		switch value := arguments[0].(type) {
+		case int64, float64, *big.Int, *Decimal:
+			return toFloat(value)
+		case string:
+			if f, err := strconv.ParseFloat(value, 64); err == nil {
+				return f
+			}
+			panic(&RuntimeError{code: TYPE_ERROR, message: fmt.Sprintf("Can't convert \"%s\" to a float.", value)})
+		}
+		panic(&RuntimeError{code: TYPE_ERROR, message: "Argument to 'float' must be a number or string."})
+	},
+}
+
 type LoxFunction struct {
 	declaration   *ast.Function
 	closure       *Environment
+	tables        *sideTables
 	isInitializer bool
 }
 
-func NewLoxFunction(declaration *ast.Function, closure *Environment, isInitializer bool) *LoxFunction {
+// A function keeps the side tables of the program that declared it, since it
+// may be called while another program is running.
+func NewLoxFunction(declaration *ast.Function, closure *Environment, tables *sideTables, isInitializer bool) *LoxFunction {
 	return &LoxFunction{
 		declaration:   declaration,
 		closure:       closure,
+		tables:        tables,
 		isInitializer: isInitializer,
 	}
 }
 
+// Tail calls made by the body come back here rather than being called
+// directly, so a chain of them runs as a loop in a single Go frame.
 func (l *LoxFunction) Call(interpreter *Interpreter, arguments []any) any {
-	environment := NewEnvironment(l.closure)
-	for i, param := range l.declaration.Params {
-		environment.Define(param.Lexeme, arguments[i])
-	}
+	function, tables := l, interpreter.tables
+	for {
+		interpreter.tables = function.tables
+		environment := NewEnvironment(function.closure)
+		for i, param := range function.declaration.Params {
+			environment.Define(param.Lexeme, arguments[i])
+		}
+
+		interpreter.executeBlock(function.declaration.Body, environment)
 
-	interpreter.executeBlock(l.declaration.Body, environment)
+		next := interpreter.activeTailCall
+		if next == nil {
+			break
+		}
+		interpreter.resetReturnValue()
+		function, arguments = next.function, next.arguments
+		interpreter.replaceFrame(function, next.call)
+	}
+	interpreter.tables = tables
 
-	if l.isInitializer {
-		this, err := l.closure.GetAt(0, "this")
+	if function.isInitializer {
+		this, err := function.closure.GetAt(0, "this")
 		if err != nil {
 			panic(err)
 		}
@@ -90,5 +205,5 @@ func (l *LoxFunction) String() string {
 func (l *LoxFunction) Bind(instance *LoxInstance) *LoxFunction {
 	environment := NewEnvironment(l.closure)
 	environment.Define("this", instance)
-	return NewLoxFunction(l.declaration, environment, l.isInitializer)
+	return NewLoxFunction(l.declaration, environment, l.tables, l.isInitializer)
 }
@@ -28,7 +28,7 @@ func (l *LoxClass) String() string {
 }
 
 func (l *LoxClass) Call(interpreter *Interpreter, arguments []any) any {
-	instance := NewLoxInstance(l)
+	instance := interpreter.newInstance(l)
 	initializer := l.FindMethod("init")
 	if initializer != nil {
 		initializer.Bind(instance).Call(interpreter, arguments)
@@ -57,3 +57,23 @@ func (l *LoxClass) FindMethod(name string) *LoxFunction {
 
 	return nil
 }
+
+// Returns the names of the methods of the class and its superclasses.
+func (l *LoxClass) methodNames() []string {
+	names := []string{}
+	for class := l; class != nil; class = class.superclass {
+		for name := range class.methods {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func (l *LoxClass) IsSubclassOf(other *LoxClass) bool {
+	for class := l; class != nil; class = class.superclass {
+		if class == other {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,213 @@
+package interpreter
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+var (
+	_ fmt.Stringer = (*Decimal)(nil)
+)
+
+// The number of fractional digits kept when a quotient doesn't terminate.
+const decimalDivisionScale = 18
+
+var bigTen = big.NewInt(10)
+
+// Parsed decimals can't be scaled by more than this power of ten either way,
+// so that a short literal can't ask for an enormous number.
+const maxDecimalExponent = 10_000
+
+var errDecimalRange = errors.New("decimal out of range")
+
+// Decimal is an exact decimal number, unscaled × 10^-scale. The scale is
+// kept through addition, subtraction and multiplication, so 1.10d prints as
+// 1.10 rather than 1.1.
+type Decimal struct {
+	unscaled *big.Int
+	scale    int
+}
+
+func NewDecimal(unscaled *big.Int, scale int) *Decimal {
+	return &Decimal{unscaled: unscaled, scale: scale}
+}
+
+func decimalFromInt(i *big.Int) *Decimal {
+	return NewDecimal(new(big.Int).Set(i), 0)
+}
+
+// Accepts an optional sign, digits, an optional fractional part and an
+// optional exponent. A number scaled by more than maxDecimalExponent gives an
+// error wrapping errDecimalRange.
+func ParseDecimal(s string) (*Decimal, error) {
+	digits := s
+	sign := ""
+	if strings.HasPrefix(digits, "-") || strings.HasPrefix(digits, "+") {
+		sign, digits = digits[:1], digits[1:]
+	}
+
+	exponent := 0
+	if i := strings.IndexAny(digits, "eE"); i >= 0 {
+		e, err := strconv.Atoi(digits[i+1:])
+		if errors.Is(err, strconv.ErrRange) {
+			return nil, fmt.Errorf("%w: %s", errDecimalRange, s)
+		}
+		if err != nil {
+			return nil, errors.New("invalid decimal " + s)
+		}
+		digits, exponent = digits[:i], e
+	}
+
+	whole, fraction, _ := strings.Cut(digits, ".")
+	if whole == "" && fraction == "" {
+		return nil, errors.New("invalid decimal " + s)
+	}
+	for _, c := range whole + fraction {
+		if c < '0' || c > '9' {
+			return nil, errors.New("invalid decimal " + s)
+		}
+	}
+
+	unscaled, ok := new(big.Int).SetString(sign+whole+fraction, 10)
+	if !ok {
+		return nil, errors.New("invalid decimal " + s)
+	}
+	scale := len(fraction) - exponent
+	if scale > maxDecimalExponent || scale < -maxDecimalExponent {
+		return nil, fmt.Errorf("%w: %s", errDecimalRange, s)
+	}
+	if scale < 0 {
+		return NewDecimal(unscaled.Mul(unscaled, pow10(-scale)), 0), nil
+	}
+	return NewDecimal(unscaled, scale), nil
+}
+
+func (d *Decimal) String() string {
+	digits := new(big.Int).Abs(d.unscaled).String()
+	sign := ""
+	if d.unscaled.Sign() < 0 {
+		sign = "-"
+	}
+	if d.scale == 0 {
+		return sign + digits
+	}
+
+	if len(digits) <= d.scale {
+		digits = strings.Repeat("0", d.scale-len(digits)+1) + digits
+	}
+	point := len(digits) - d.scale
+	return sign + digits[:point] + "." + digits[point:]
+}
+
+func (d *Decimal) Rat() *big.Rat {
+	return new(big.Rat).SetFrac(d.unscaled, pow10(d.scale))
+}
+
+func (d *Decimal) Sign() int {
+	return d.unscaled.Sign()
+}
+
+func (d *Decimal) IsInteger() bool {
+	return d.Rat().IsInt()
+}
+
+func (d *Decimal) Neg() *Decimal {
+	return NewDecimal(new(big.Int).Neg(d.unscaled), d.scale)
+}
+
+func (d *Decimal) Add(other *Decimal) *Decimal {
+	l, r, scale := alignDecimals(d, other)
+	return NewDecimal(l.Add(l, r), scale)
+}
+
+func (d *Decimal) Sub(other *Decimal) *Decimal {
+	l, r, scale := alignDecimals(d, other)
+	return NewDecimal(l.Sub(l, r), scale)
+}
+
+func (d *Decimal) Mul(other *Decimal) *Decimal {
+	return NewDecimal(new(big.Int).Mul(d.unscaled, other.unscaled), d.scale+other.scale)
+}
+
+// Quotients are rounded half-to-even to decimalDivisionScale digits, then
+// trailing zeros beyond the larger operand scale are dropped, so that
+// 1.00d / 4d is 0.25 and 1d / 3d is 0.333333333333333333. The divisor must
+// not be zero.
+func (d *Decimal) Quo(other *Decimal) *Decimal {
+	quotient := new(big.Rat).Quo(d.Rat(), other.Rat())
+	result := NewDecimal(roundRat(quotient, decimalDivisionScale), decimalDivisionScale)
+	return result.trim(max(d.scale, other.scale))
+}
+
+func (d *Decimal) Cmp(other *Decimal) int {
+	l, r, _ := alignDecimals(d, other)
+	return l.Cmp(r)
+}
+
+// Rounds to the given number of fractional digits, half away from zero.
+func (d *Decimal) Round(scale int) *Decimal {
+	if scale >= d.scale {
+		return NewDecimal(new(big.Int).Mul(d.unscaled, pow10(scale-d.scale)), scale)
+	}
+
+	divisor := pow10(d.scale - scale)
+	quotient, remainder := new(big.Int).QuoRem(d.unscaled, divisor, new(big.Int))
+	if new(big.Int).Mul(new(big.Int).Abs(remainder), big.NewInt(2)).Cmp(divisor) >= 0 {
+		quotient.Add(quotient, big.NewInt(int64(d.unscaled.Sign())))
+	}
+	return NewDecimal(quotient, scale)
+}
+
+// Rounds towards negative infinity to a whole number.
+func (d *Decimal) Floor() *big.Int {
+	// Euclidean division by a positive divisor rounds down
+	return new(big.Int).Div(d.unscaled, pow10(d.scale))
+}
+
+func (d *Decimal) trim(minimumScale int) *Decimal {
+	unscaled := new(big.Int).Set(d.unscaled)
+	scale := d.scale
+	remainder := new(big.Int)
+	for scale > minimumScale {
+		quotient, _ := new(big.Int).QuoRem(unscaled, bigTen, remainder)
+		if remainder.Sign() != 0 {
+			break
+		}
+		unscaled = quotient
+		scale--
+	}
+	return NewDecimal(unscaled, scale)
+}
+
+func alignDecimals(left *Decimal, right *Decimal) (*big.Int, *big.Int, int) {
+	scale := max(left.scale, right.scale)
+	l := new(big.Int).Mul(left.unscaled, pow10(scale-left.scale))
+	r := new(big.Int).Mul(right.unscaled, pow10(scale-right.scale))
+	return l, r, scale
+}
+
+// Rounds half-to-even to the given number of fractional digits, returning
+// the unscaled value.
+func roundRat(r *big.Rat, scale int) *big.Int {
+	numerator := new(big.Int).Mul(r.Num(), pow10(scale))
+	denominator := r.Denom()
+	quotient, remainder := new(big.Int).QuoRem(numerator, denominator, new(big.Int))
+
+	twiceRemainder := new(big.Int).Mul(new(big.Int).Abs(remainder), big.NewInt(2))
+	switch twiceRemainder.Cmp(denominator) {
+	case 1:
+		quotient.Add(quotient, big.NewInt(int64(numerator.Sign())))
+	case 0:
+		if quotient.Bit(0) == 1 {
+			quotient.Add(quotient, big.NewInt(int64(numerator.Sign())))
+		}
+	}
+	return quotient
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(bigTen, big.NewInt(int64(n)), nil)
+}
@@ -0,0 +1,321 @@
+package interpreter
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/DanielleB-R/golox/interpreter/token"
+)
+
+type Phase int
+
+const (
+	SCAN_PHASE Phase = iota
+	PARSE_PHASE
+	RESOLVE_PHASE
+	RUNTIME_PHASE
+)
+
+var phaseNames = []string{"scan", "parse", "resolve", "runtime"}
+
+func (p Phase) String() string {
+	return phaseNames[p]
+}
+
+type Severity int
+
+const (
+	ERROR_SEVERITY Severity = iota
+	WARNING_SEVERITY
+)
+
+func (s Severity) String() string {
+	if s == WARNING_SEVERITY {
+		return "warning"
+	}
+	return "error"
+}
+
+// Error codes say what kind of error it is. The second digit is the phase
+// the error came from, and the code ending in 00 is for errors of that phase
+// that don't have a kind of their own.
+const (
+	SCAN_ERROR                 = "E0100"
+	UNEXPECTED_CHARACTER_ERROR = "E0101"
+	UNTERMINATED_STRING_ERROR  = "E0102"
+	UNTERMINATED_COMMENT_ERROR = "E0103"
+	MALFORMED_NUMBER_ERROR     = "E0104"
+	NUMBER_RANGE_ERROR         = "E0105"
+
+	PARSE_ERROR               = "E0200"
+	EXPECTED_TOKEN_ERROR      = "E0201"
+	EXPECTED_EXPRESSION_ERROR = "E0202"
+	ASSIGNMENT_TARGET_ERROR   = "E0203"
+	TOO_MANY_ARGUMENTS_ERROR  = "E0204"
+	PATTERN_SYNTAX_ERROR      = "E0205"
+
+	RESOLVE_ERROR             = "E0300"
+	MISPLACED_KEYWORD_ERROR   = "E0301"
+	DUPLICATE_NAME_ERROR      = "E0302"
+	SELF_INHERITANCE_ERROR    = "E0303"
+	OWN_INITIALIZER_ERROR     = "E0304"
+	UNDEFINED_LABEL_ERROR     = "E0305"
+	ALTERNATIVE_BINDING_ERROR = "E0306"
+
+	RUNTIME_ERROR            = "E0400"
+	UNDEFINED_VARIABLE_ERROR = "E0401"
+	UNDEFINED_PROPERTY_ERROR = "E0402"
+	TYPE_ERROR               = "E0403"
+	ARITY_ERROR              = "E0404"
+	RANGE_ERROR              = "E0405"
+	ARITHMETIC_ERROR         = "E0406"
+	MATCH_ERROR              = "E0407"
+	NATIVE_ERROR             = "E0408"
+	INTERRUPTED_ERROR        = "E0409"
+	LIMIT_ERROR              = "E0410"
+)
+
+// Diagnostic is implemented by every error and warning the interpreter
+// reports, so hosts can use errors.As to get at the details rather than
+// matching on the text. The span is invalid when the problem isn't tied to
+// any source.
+type Diagnostic interface {
+	Phase() Phase
+	Severity() Severity
+	Code() string
+	Message() string
+	Span() token.Span
+	// Suggestions on how to fix the problem
+	Notes() []string
+}
+
+// Returns every diagnostic in err, including those in lists of errors such as
+// ParseErrors, in order.
+func Diagnostics(err error) []Diagnostic {
+	switch err := err.(type) {
+	case Diagnostic:
+		return []Diagnostic{err}
+	case interface{ Unwrap() []error }:
+		diagnostics := []Diagnostic{}
+		for _, e := range err.Unwrap() {
+			diagnostics = append(diagnostics, Diagnostics(e)...)
+		}
+		return diagnostics
+	case interface{ Unwrap() error }:
+		return Diagnostics(err.Unwrap())
+	}
+	return nil
+}
+
+// Writes out errors and warnings for people or for tools.
+type Renderer interface {
+	Render(w io.Writer, err error)
+	RenderWarning(w io.Writer, warning *Warning)
+}
+
+var (
+	_ Renderer = (*DiagnosticRenderer)(nil)
+	_ Renderer = (*JSONRenderer)(nil)
+)
+
+// ANSI escapes used when colour is on
+const (
+	colourReset  = "\x1b[0m"
+	colourBold   = "\x1b[1m"
+	colourRed    = "\x1b[1;31m"
+	colourYellow = "\x1b[1;33m"
+	colourBlue   = "\x1b[1;34m"
+	colourCyan   = "\x1b[1;36m"
+)
+
+// Renders errors with an excerpt of the source they came from, like
+//
+//	error[E0201]: Expect ';' after value.
+//	 --> main.lox:3:9
+//	  |
+//	3 | print a b
+//	  |         ^
+type DiagnosticRenderer struct {
+	lines  []string
+	colour bool
+}
+
+func NewDiagnosticRenderer(source string, colour bool) *DiagnosticRenderer {
+	return &DiagnosticRenderer{lines: strings.Split(source, "\n"), colour: colour}
+}
+
+// Colour is used when the output is a terminal, unless NO_COLOR is set.
+func UseColour(f *os.File) bool {
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// Writes every diagnostic in err, falling back to its plain message when it
+// has none.
+func (r *DiagnosticRenderer) Render(w io.Writer, err error) {
+	diagnostics := Diagnostics(err)
+	if len(diagnostics) == 0 {
+		fmt.Fprintln(w, err)
+	}
+	for _, d := range diagnostics {
+		r.render(w, d)
+	}
+}
+
+func (r *DiagnosticRenderer) RenderWarning(w io.Writer, warning *Warning) {
+	r.render(w, warning)
+}
+
+func (r *DiagnosticRenderer) render(w io.Writer, d Diagnostic) {
+	colour := colourRed
+	if d.Severity() == WARNING_SEVERITY {
+		colour = colourYellow
+	}
+	fmt.Fprintf(w, "%s: %s\n", r.paint(colour, fmt.Sprintf("%s[%s]", d.Severity(), d.Code())), r.paint(colourBold, d.Message()))
+
+	span := d.Span()
+	line := span.Start.Line
+	if span.IsValid() && line <= len(r.lines) {
+		source := r.lines[line-1]
+		number := strconv.Itoa(line)
+		gutter := strings.Repeat(" ", len(number))
+
+		fmt.Fprintf(w, "%s%s %s\n", gutter, r.paint(colourBlue, "-->"), span.Start)
+		fmt.Fprintf(w, "%s %s\n", gutter, r.paint(colourBlue, "|"))
+		fmt.Fprintf(w, "%s %s %s\n", r.paint(colourBlue, number), r.paint(colourBlue, "|"), source)
+		padding, marks := underline(source, span)
+		fmt.Fprintf(w, "%s %s %s%s\n", gutter, r.paint(colourBlue, "|"), padding, r.paint(colour, marks))
+	}
+
+	for _, note := range d.Notes() {
+		fmt.Fprintf(w, "  %s %s\n", r.paint(colourCyan, "= help:"), note)
+	}
+	if trace := stackTrace(d); len(trace) > 0 {
+		fmt.Fprintf(w, "  %s\n", r.paint(colourCyan, "= traceback, most recent call first:"))
+		// Runaway recursion repeats the same frame thousands of times
+		for n := 0; n < len(trace); {
+			repeats := 1
+			for n+repeats < len(trace) && trace[n+repeats] == trace[n] {
+				repeats++
+			}
+			fmt.Fprintf(w, "      %s\n", trace[n])
+			if repeats > 1 {
+				fmt.Fprintf(w, "      ... repeated %d more times\n", repeats-1)
+			}
+			n += repeats
+		}
+	}
+}
+
+func (r *DiagnosticRenderer) paint(colour string, text string) string {
+	if !r.colour {
+		return text
+	}
+	return colour + text + colourReset
+}
+
+// Underlines the part of the line the span covers, or the rest of the line if
+// the span goes on past it. Tabs in the padding before the span are kept so
+// that the underline lines up.
+func underline(line string, span token.Span) (string, string) {
+	var b strings.Builder
+	column := 1
+	for _, c := range line {
+		if column >= span.Start.Column {
+			break
+		}
+		if c == '\t' {
+			b.WriteRune('\t')
+		} else {
+			b.WriteRune(' ')
+		}
+		column++
+	}
+
+	length := utf8.RuneCountInString(line) - span.Start.Column + 1
+	if span.End.Line == span.Start.Line {
+		length = span.End.Column - span.Start.Column
+	}
+	return b.String(), "^" + strings.Repeat("~", max(length-1, 0))
+}
+
+func stackTrace(d Diagnostic) []Frame {
+	if runtimeError, ok := d.(*RuntimeError); ok {
+		return runtimeError.StackTrace()
+	}
+	return nil
+}
+
+// Writes one JSON object per line for each diagnostic, for tools such as CI
+// systems to read.
+type JSONRenderer struct{}
+
+type jsonDiagnostic struct {
+	Severity  string   `json:"severity"`
+	Phase     string   `json:"phase,omitempty"`
+	Code      string   `json:"code,omitempty"`
+	Message   string   `json:"message"`
+	File      string   `json:"file,omitempty"`
+	Line      int      `json:"line,omitempty"`
+	Column    int      `json:"column,omitempty"`
+	Offset    int      `json:"offset"`
+	EndLine   int      `json:"endLine,omitempty"`
+	EndColumn int      `json:"endColumn,omitempty"`
+	EndOffset int      `json:"endOffset"`
+	Notes     []string `json:"notes,omitempty"`
+	Trace     []string `json:"trace,omitempty"`
+}
+
+func (r *JSONRenderer) Render(w io.Writer, err error) {
+	diagnostics := Diagnostics(err)
+	if len(diagnostics) == 0 {
+		r.write(w, jsonDiagnostic{Severity: ERROR_SEVERITY.String(), Message: err.Error()})
+	}
+	for _, d := range diagnostics {
+		r.render(w, d)
+	}
+}
+
+func (r *JSONRenderer) RenderWarning(w io.Writer, warning *Warning) {
+	r.render(w, warning)
+}
+
+func (r *JSONRenderer) render(w io.Writer, d Diagnostic) {
+	span := d.Span()
+	trace := []string{}
+	for _, frame := range stackTrace(d) {
+		trace = append(trace, frame.String())
+	}
+	r.write(w, jsonDiagnostic{
+		Severity:  d.Severity().String(),
+		Phase:     d.Phase().String(),
+		Code:      d.Code(),
+		Message:   d.Message(),
+		File:      span.Start.File,
+		Line:      span.Start.Line,
+		Column:    span.Start.Column,
+		Offset:    span.Start.Offset,
+		EndLine:   span.End.Line,
+		EndColumn: span.End.Column,
+		EndOffset: span.End.Offset,
+		Notes:     d.Notes(),
+		Trace:     trace,
+	})
+}
+
+func (r *JSONRenderer) write(w io.Writer, d jsonDiagnostic) {
+	encoded, err := json.Marshal(d)
+	if err != nil {
+		// Every field is a string, int or slice of strings
+		panic(err)
+	}
+	fmt.Fprintf(w, "%s\n", encoded)
+}
@@ -0,0 +1,178 @@
+package interpreter
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func render(source string, err error, colour bool) string {
+	var out strings.Builder
+	NewDiagnosticRenderer(source, colour).Render(&out, err)
+	return out.String()
+}
+
+func TestRenderScanErrors(t *testing.T) {
+	source := "var a = 1;\nvar b = 12abc;\nvar c = @;"
+	_, err := scan(source)
+	require.Error(t, err)
+	require.Equal(t, `error[E0104]: Invalid suffix 'abc' on number literal.
+ --> 2:9
+  |
+2 | var b = 12abc;
+  |         ^~~~~
+  = help: Use 'n' for a big integer or 'd' for a decimal.
+error[E0101]: Unexpected character.
+ --> 3:9
+  |
+3 | var c = @;
+  |         ^
+`, render(source, err, false))
+}
+
+func TestRenderParseErrorKeepsTabs(t *testing.T) {
+	source := "var a = 1;\n\tprint a b;"
+	tokens, err := scan(source)
+	require.NoError(t, err)
+	_, err = NewParser(tokens).Parse()
+	require.Error(t, err)
+	require.Equal(t, "error[E0201]: Expect ';' after expression.\n --> 2:10\n  |\n2 | \tprint a b;\n  | \t        ^\n", render(source, err, false))
+}
+
+func TestRenderRuntimeErrorFromFile(t *testing.T) {
+	source := "var a = \"x\";\n\nprint -a;"
+	err := runSource("main.lox", source, NewInterpreter(), nil)
+	require.Error(t, err)
+	require.Equal(t, `error[E0403]: Operand must be a number
+ --> main.lox:3:7
+  |
+3 | print -a;
+  |       ^
+`, render(source, err, false))
+}
+
+func TestRenderSpanPastEndOfLine(t *testing.T) {
+	source := "var a = 1; /* never\nclosed"
+	_, err := scan(source)
+	require.Error(t, err)
+	require.Contains(t, render(source, err, false), "1 | var a = 1; /* never\n  |            ^~~~~~~~\n")
+}
+
+func TestRenderWithColour(t *testing.T) {
+	source := "print @;"
+	_, err := scan(source)
+	require.Error(t, err)
+	rendered := render(source, err, true)
+	require.Contains(t, rendered, "\x1b[1;31merror[E0101]\x1b[0m: \x1b[1mUnexpected character.\x1b[0m\n")
+	require.Contains(t, rendered, "\x1b[1;31m^\x1b[0m")
+}
+
+func TestRenderOtherErrorsPlainly(t *testing.T) {
+	require.Equal(t, "something broke\n", render("", errors.New("something broke"), false))
+}
+
+func TestRenderTraceback(t *testing.T) {
+	source := "fun f(x) {\n  return -x;\n}\nprint f(\"a\");"
+	err := runSource("main.lox", source, NewInterpreter(), nil)
+	require.Error(t, err)
+	require.Equal(t, `error[E0403]: Operand must be a number
+ --> main.lox:2:10
+  |
+2 |   return -x;
+  |          ^
+  = traceback, most recent call first:
+      in f, called at main.lox:4:7
+`, render(source, err, false))
+}
+
+func TestRenderFoldsRepeatedFrames(t *testing.T) {
+	source := "fun f(n) {\n  if (n == 0) return nil + 1;\n  return 1 + f(n - 1);\n}\nf(5);"
+	err := runSource("main.lox", source, NewInterpreter(), nil)
+	require.Error(t, err)
+	require.Contains(t, render(source, err, false), `  = traceback, most recent call first:
+      in f, called at main.lox:3:14
+      ... repeated 4 more times
+      in f, called at main.lox:5:1
+`)
+}
+
+func TestDiagnosticsWorkWithErrorsAs(t *testing.T) {
+	_, err := scan("var a = 1;\nvar b = @;")
+	require.Error(t, err)
+
+	var diagnostic Diagnostic
+	require.True(t, errors.As(err, &diagnostic))
+	require.Equal(t, SCAN_PHASE, diagnostic.Phase())
+	require.Equal(t, ERROR_SEVERITY, diagnostic.Severity())
+	require.Equal(t, "E0101", diagnostic.Code())
+	require.Equal(t, "Unexpected character.", diagnostic.Message())
+	require.Equal(t, 2, diagnostic.Span().Start.Line)
+
+	var sourceError *SourceError
+	require.True(t, errors.As(err, &sourceError))
+
+	err = run(`print nil - 1;`, NewInterpreter())
+	var runtimeError *RuntimeError
+	require.True(t, errors.As(err, &runtimeError))
+	require.Equal(t, RUNTIME_PHASE, runtimeError.Phase())
+	require.Equal(t, "Operands must be numbers", runtimeError.Message())
+}
+
+func TestDiagnosticsFlattensLists(t *testing.T) {
+	err := resolve(t, "return 1;\n{ var a = a; }")
+	diagnostics := Diagnostics(err)
+	require.Len(t, diagnostics, 2)
+	require.Equal(t, RESOLVE_PHASE, diagnostics[0].Phase())
+	require.Equal(t, "Can't return from top-level code", diagnostics[0].Message())
+	require.Equal(t, "Can't read local variable in its own initializer", diagnostics[1].Message())
+
+	require.Nil(t, Diagnostics(errors.New("plain")))
+}
+
+func TestRenderJSON(t *testing.T) {
+	source := "fun f(x) {\n  return -x;\n}\nf(\"a\");"
+	err := runSource("main.lox", source, NewInterpreter(), nil)
+	require.Error(t, err)
+
+	var out strings.Builder
+	renderer := &JSONRenderer{}
+	renderer.Render(&out, err)
+	renderer.Render(&out, errors.New("plain"))
+	renderer.RenderWarning(&out, &Warning{category: UNUSED_VARIABLE, message: "Local 'a' is never read"})
+	require.Equal(t, `{"severity":"error","phase":"runtime","code":"E0403","message":"Operand must be a number","file":"main.lox","line":2,"column":10,"offset":20,"endLine":2,"endColumn":11,"endOffset":21,"trace":["in f, called at main.lox:4:1"]}
+{"severity":"error","message":"plain","offset":0,"endOffset":0}
+{"severity":"warning","phase":"resolve","code":"W0301","message":"Local 'a' is never read","offset":0,"endOffset":0}
+`, out.String())
+}
+
+func TestErrorKindsHaveTheirOwnCodes(t *testing.T) {
+	cases := map[string]string{
+		"var a = @;":                     UNEXPECTED_CHARACTER_ERROR,
+		`print "open;`:                   UNTERMINATED_STRING_ERROR,
+		"print 0b12;":                    MALFORMED_NUMBER_ERROR,
+		"print 1 +;":                     EXPECTED_EXPRESSION_ERROR,
+		"print 1":                        EXPECTED_TOKEN_ERROR,
+		"1 = 2;":                         ASSIGNMENT_TARGET_ERROR,
+		"return 1;":                      MISPLACED_KEYWORD_ERROR,
+		"{ var a = 1; var a = 2; }":      DUPLICATE_NAME_ERROR,
+		"class A < A {}":                 SELF_INHERITANCE_ERROR,
+		"print missing;":                 UNDEFINED_VARIABLE_ERROR,
+		`print "abc".size();`:            UNDEFINED_PROPERTY_ERROR,
+		"print -nil;":                    TYPE_ERROR,
+		"fun f(a) {} f();":               ARITY_ERROR,
+		"print bigint(1) / bigint(0);":   ARITHMETIC_ERROR,
+		`print "a,b".split(",").get(5);`: RANGE_ERROR,
+		"var [a] = 1;":                   MATCH_ERROR,
+	}
+
+	for source, code := range cases {
+		t.Run(source, func(t *testing.T) {
+			err := run(source, NewInterpreter())
+			diagnostics := Diagnostics(err)
+			require.NotEmpty(t, diagnostics)
+			require.Equal(t, code, diagnostics[0].Code())
+		})
+	}
+}
@@ -27,19 +27,14 @@ func (e *Environment) Define(name string, value any) {
 }
 
 func (e *Environment) Assign(name *token.Token, value any) error {
-	if _, ok := e.values[name.Lexeme]; ok {
-		e.values[name.Lexeme] = value
-		return nil
+	for environment := e; environment != nil; environment = environment.enclosing {
+		if _, ok := environment.values[name.Lexeme]; ok {
+			environment.values[name.Lexeme] = value
+			return nil
+		}
 	}
 
-	if e.enclosing != nil {
-		return e.enclosing.Assign(name, value)
-	}
-
-	return &RuntimeError{
-		token:   name,
-		message: fmt.Sprintf("Undefined variable '%s'.", name.Lexeme),
-	}
+	return e.undefinedVariable(name)
 }
 
 func (e *Environment) AssignAt(distance int, name *token.Token, value any) {
@@ -47,17 +42,28 @@ func (e *Environment) AssignAt(distance int, name *token.Token, value any) {
 }
 
 func (e *Environment) Get(name *token.Token) (any, error) {
-	if value, ok := e.values[name.Lexeme]; ok {
-		return value, nil
+	for environment := e; environment != nil; environment = environment.enclosing {
+		if value, ok := environment.values[name.Lexeme]; ok {
+			return value, nil
+		}
 	}
 
-	if e.enclosing != nil {
-		return e.enclosing.Get(name)
+	return nil, e.undefinedVariable(name)
+}
+
+// Suggests a name from this environment or any enclosing one.
+func (e *Environment) undefinedVariable(name *token.Token) *RuntimeError {
+	candidates := []string{}
+	for environment := e; environment != nil; environment = environment.enclosing {
+		for candidate := range environment.values {
+			candidates = append(candidates, candidate)
+		}
 	}
 
-	return nil, &RuntimeError{
+	return &RuntimeError{
 		token:   name,
-		message: fmt.Sprintf("Undefined variable '%s'.", name.Lexeme),
+		code:    UNDEFINED_VARIABLE_ERROR,
+		message: fmt.Sprintf("Undefined variable '%s'.", name.Lexeme) + suggestion(name.Lexeme, candidates),
 	}
 }
 
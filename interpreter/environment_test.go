@@ -184,3 +184,19 @@ func TestAssignUndefinedVariableDoesNotDefineIt(t *testing.T) {
 	require.Error(t, err)
 	require.Len(t, environment.values, 0)
 }
+
+func TestUndefinedVariableSuggestsNearMatch(t *testing.T) {
+	globals := NewEnvironment(nil)
+	globals.Define("counter", 1)
+	local := NewEnvironment(globals)
+	local.Define("total", 2)
+
+	_, err := local.Get(tokenNamed("totl"))
+	require.EqualError(t, err, "Runtime error line 0: Undefined variable 'totl'. Did you mean 'total'?")
+
+	err = local.Assign(tokenNamed("conuter"), 3)
+	require.EqualError(t, err, "Runtime error line 0: Undefined variable 'conuter'. Did you mean 'counter'?")
+
+	_, err = local.Get(tokenNamed("zebra"))
+	require.EqualError(t, err, "Runtime error line 0: Undefined variable 'zebra'.")
+}
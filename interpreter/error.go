@@ -4,24 +4,59 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/DanielleB-R/golox/interpreter/ast"
 	"github.com/DanielleB-R/golox/interpreter/token"
 )
 
 var _ error = (*SourceError)(nil)
 var _ error = (*ParseError)(nil)
 
+var (
+	_ Diagnostic = (*SourceError)(nil)
+	_ Diagnostic = (*ParseError)(nil)
+	_ Diagnostic = (*ResolverError)(nil)
+	_ Diagnostic = (*RuntimeError)(nil)
+)
+
 type SourceError struct {
-	line    int
+	span    token.Span
 	where   string
+	code    string
 	message string
+	notes   []string
+}
+
+func NewSourceError(span token.Span, where string, message string) *SourceError {
+	return &SourceError{span: span, where: where, message: message}
+}
+
+func (s *SourceError) Span() token.Span {
+	return s.span
+}
+
+func (*SourceError) Phase() Phase {
+	return SCAN_PHASE
+}
+
+func (*SourceError) Severity() Severity {
+	return ERROR_SEVERITY
+}
+
+func (s *SourceError) Code() string {
+	return codeOr(s.code, SCAN_ERROR)
+}
+
+func (s *SourceError) Message() string {
+	return s.message
 }
 
-func NewSourceError(line int, where string, message string) *SourceError {
-	return &SourceError{line, where, message}
+func (s *SourceError) Notes() []string {
+	return s.notes
 }
 
 func (s *SourceError) Error() string {
-	return fmt.Sprintf("[line %d] Error%s: %s", s.line, s.where, s.message)
+	start := s.span.Start
+	return fmt.Sprintf("%s[line %d, column %d] Error%s: %s", filePrefix(start.File), start.Line, start.Column, s.where, s.message)
 }
 
 type SourceErrors []*SourceError
@@ -35,16 +70,45 @@ func (s SourceErrors) Error() string {
 	return strings.Join(errorStrings, "\n")
 }
 
+func (s SourceErrors) Unwrap() []error {
+	return unwrapAll(s)
+}
+
 type ParseError struct {
 	token   *token.Token
+	code    string
 	message string
 }
 
+func (p *ParseError) Span() token.Span {
+	return p.token.Span()
+}
+
+func (*ParseError) Phase() Phase {
+	return PARSE_PHASE
+}
+
+func (*ParseError) Severity() Severity {
+	return ERROR_SEVERITY
+}
+
+func (p *ParseError) Code() string {
+	return codeOr(p.code, PARSE_ERROR)
+}
+
+func (p *ParseError) Message() string {
+	return p.message
+}
+
+func (*ParseError) Notes() []string {
+	return nil
+}
+
 func (p *ParseError) Error() string {
 	if p.token.TokenType == token.EOF {
-		return fmt.Sprintf("[line %d at end] Error: %s", p.token.Line, p.message)
+		return fmt.Sprintf("%s[line %d at end] Error: %s", filePrefix(p.token.File), p.token.Line, p.message)
 	}
-	return fmt.Sprintf("[line %d at '%s'] Error: %s", p.token.Line, p.token.Lexeme, p.message)
+	return fmt.Sprintf("%s[line %d at '%s'] Error: %s", filePrefix(p.token.File), p.token.Line, p.token.Lexeme, p.message)
 }
 
 type ParseErrors []*ParseError
@@ -58,20 +122,157 @@ func (s ParseErrors) Error() string {
 	return strings.Join(errorStrings, "\n")
 }
 
+func (s ParseErrors) Unwrap() []error {
+	return unwrapAll(s)
+}
+
 type ResolverError struct {
 	token   *token.Token
+	code    string
 	message string
 }
 
+type ResolverErrors []*ResolverError
+
+func (s ResolverErrors) Error() string {
+	errorStrings := []string{}
+	for _, e := range s {
+		errorStrings = append(errorStrings, e.Error())
+	}
+
+	return strings.Join(errorStrings, "\n")
+}
+
+func (s ResolverErrors) Unwrap() []error {
+	return unwrapAll(s)
+}
+
+func (r *ResolverError) Span() token.Span {
+	return r.token.Span()
+}
+
+func (*ResolverError) Phase() Phase {
+	return RESOLVE_PHASE
+}
+
+func (*ResolverError) Severity() Severity {
+	return ERROR_SEVERITY
+}
+
+func (r *ResolverError) Code() string {
+	return codeOr(r.code, RESOLVE_ERROR)
+}
+
+func (r *ResolverError) Message() string {
+	return r.message
+}
+
+func (*ResolverError) Notes() []string {
+	return nil
+}
+
 func (r *ResolverError) Error() string {
-	return fmt.Sprintf("Name resolution error line %d: %s", r.token.Line, r.message)
+	return fmt.Sprintf("%sName resolution error line %d: %s", filePrefix(r.token.File), r.token.Line, r.message)
 }
 
 type RuntimeError struct {
-	token   *token.Token
-	message string
+	token      *token.Token
+	code       string
+	message    string
+	stackTrace []Frame
+	// The error returned by a native function, if that's where this came from
+	cause error
+}
+
+// Returns the calls that were in progress when the error happened, innermost
+// first. It is empty for errors in top-level code.
+func (r *RuntimeError) StackTrace() []Frame {
+	return r.stackTrace
+}
+
+func (r *RuntimeError) Span() token.Span {
+	if r.token == nil {
+		return token.Span{}
+	}
+	return r.token.Span()
+}
+
+func (*RuntimeError) Phase() Phase {
+	return RUNTIME_PHASE
+}
+
+func (*RuntimeError) Severity() Severity {
+	return ERROR_SEVERITY
+}
+
+func (r *RuntimeError) Code() string {
+	return codeOr(r.code, RUNTIME_ERROR)
+}
+
+func (r *RuntimeError) Message() string {
+	return r.message
+}
+
+func (*RuntimeError) Notes() []string {
+	return nil
+}
+
+func (r *RuntimeError) Unwrap() error {
+	return r.cause
+}
+
+// Returns a token to report a runtime error at, given a token, an expression
+// or a statement. A node that isn't a single token is stood for by a token
+// covering its span, and nil is returned when there's no source for it.
+func positionOf(at any) *token.Token {
+	var span token.Span
+	switch at := at.(type) {
+	case *token.Token:
+		return at
+	case ast.Expr:
+		span = ast.SpanOf(at)
+	case ast.Stmt:
+		span = ast.StmtSpanOf(at)
+	}
+	if !span.IsValid() {
+		return nil
+	}
+	return &token.Token{
+		File:   span.Start.File,
+		Offset: span.Start.Offset,
+		Line:   span.Start.Line,
+		Column: span.Start.Column,
+		End:    span.End,
+	}
 }
 
 func (r *RuntimeError) Error() string {
-	return fmt.Sprintf("Runtime error line %d: %s", r.token.Line, r.message)
+	if r.token == nil {
+		return fmt.Sprintf("Runtime error: %s", r.message)
+	}
+	return fmt.Sprintf("%sRuntime error line %d: %s", filePrefix(r.token.File), r.token.Line, r.message)
+}
+
+// Errors without a kind of their own get the general code for their phase.
+func codeOr(code string, phaseCode string) string {
+	if code == "" {
+		return phaseCode
+	}
+	return code
+}
+
+func unwrapAll[E error](errs []E) []error {
+	unwrapped := make([]error, len(errs))
+	for i, err := range errs {
+		unwrapped[i] = err
+	}
+	return unwrapped
+}
+
+// Errors from named files start with the file name.
+func filePrefix(file string) string {
+	if file == "" {
+		return ""
+	}
+	return file + ": "
 }
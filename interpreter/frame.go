@@ -0,0 +1,76 @@
+package interpreter
+
+import (
+	"fmt"
+
+	"github.com/DanielleB-R/golox/interpreter/ast"
+	"github.com/DanielleB-R/golox/interpreter/token"
+)
+
+// A Frame is a call in progress, kept so that a runtime error can say how it
+// was reached. Tail calls replace the frame of the function making them, so
+// ElidedTailCalls counts the frames that were replaced.
+type Frame struct {
+	Function        string
+	CallSite        token.Span
+	ElidedTailCalls int
+}
+
+func (f Frame) String() string {
+	description := fmt.Sprintf("in %s, called at %s", f.Function, f.CallSite.Start)
+	if !f.CallSite.IsValid() {
+		description = fmt.Sprintf("in %s, called from Go", f.Function)
+	}
+	switch f.ElidedTailCalls {
+	case 0:
+		return description
+	case 1:
+		return description + " (after 1 tail call)"
+	}
+	return fmt.Sprintf("%s (after %d tail calls)", description, f.ElidedTailCalls)
+}
+
+func callableName(function Callable) string {
+	switch function := function.(type) {
+	case *LoxFunction:
+		return function.declaration.Name.Lexeme
+	case *LoxClass:
+		return function.name
+	case *NativeFunction:
+		return function.name
+	}
+	return fmt.Sprint(function)
+}
+
+// Frames are popped only when a call returns normally, so that when a
+// runtime error unwinds the stack the frames it passed through are still
+// there to be recorded.
+func (i *Interpreter) pushFrame(function Callable, call *ast.Call) {
+	frame := Frame{Function: callableName(function)}
+	// Calls made by the host have no call site
+	if call != nil {
+		frame.CallSite = ast.SpanOf(call)
+	}
+	i.frames = append(i.frames, frame)
+}
+
+func (i *Interpreter) popFrame() {
+	i.frames = i.frames[:len(i.frames)-1]
+}
+
+// Records a tail call by replacing the innermost frame.
+func (i *Interpreter) replaceFrame(function Callable, call *ast.Call) {
+	top := &i.frames[len(i.frames)-1]
+	top.Function = callableName(function)
+	top.CallSite = ast.SpanOf(call)
+	top.ElidedTailCalls++
+}
+
+// Returns the frames with the innermost first.
+func (i *Interpreter) stackTrace() []Frame {
+	trace := make([]Frame, len(i.frames))
+	for n, frame := range i.frames {
+		trace[len(i.frames)-1-n] = frame
+	}
+	return trace
+}
@@ -36,12 +36,18 @@ func (l *LoxInstance) Get(name *token.Token) (any, error) {
 		return method.Bind(l), nil
 	}
 
+	candidates := l.class.methodNames()
+	for field := range l.fields {
+		candidates = append(candidates, field)
+	}
 	return nil, &RuntimeError{
 		token:   name,
-		message: fmt.Sprintf("Undefined property '%s'", name.Lexeme),
+		code:    UNDEFINED_PROPERTY_ERROR,
+		message: fmt.Sprintf("Undefined property '%s'.", name.Lexeme) + suggestion(name.Lexeme, candidates),
 	}
 }
 
-func (l *LoxInstance) Set(name *token.Token, value any) {
+func (l *LoxInstance) Set(name *token.Token, value any) error {
 	l.fields[name.Lexeme] = value
+	return nil
 }
@@ -1,7 +1,12 @@
 package interpreter
 
 import (
+	"bufio"
+	"context"
 	"fmt"
+	"io"
+	"math/big"
+	"sync/atomic"
 
 	"github.com/DanielleB-R/golox/interpreter/ast"
 	"github.com/DanielleB-R/golox/interpreter/token"
@@ -17,45 +22,152 @@ type Interpreter struct {
 	environment       *Environment
 	activeReturn      bool
 	activeReturnValue any
-	locals            map[ast.Expr]int
-}
-
-func NewInterpreter() *Interpreter {
-	globals := NewEnvironment(nil)
-	globals.Define("clock", Clock)
-	return &Interpreter{
+	activeTailCall    *tailCall
+	activeBreak       *ast.While
+	activeContinue    *ast.While
+	tables            *sideTables
+	frames            []Frame
+	stdout            io.Writer
+	stderr            io.Writer
+	stdin             *bufio.Reader
+	// The context of the program being run
+	ctx         context.Context
+	limits      Limits
+	steps       int64
+	stringBytes int64
+	instances   atomic.Int64
+}
+
+// A call in tail position is not performed by VisitReturn; it is handed back
+// to the enclosing LoxFunction.Call so that it can be run without growing the
+// Go stack.
+type tailCall struct {
+	function  *LoxFunction
+	arguments []any
+	call      *ast.Call
+}
+
+// Without options the interpreter uses the standard input and output of the
+// process.
+func NewInterpreter(options ...Option) *Interpreter {
+	globals := builtins()
+	interpreter := &Interpreter{
 		globals:           globals,
 		environment:       globals,
 		activeReturn:      false,
 		activeReturnValue: nil,
-		locals:            map[ast.Expr]int{},
+		activeTailCall:    nil,
+		activeBreak:       nil,
+		activeContinue:    nil,
+		tables:            newSideTables(),
+		ctx:               context.Background(),
 	}
+	for _, option := range append(defaultOptions(), options...) {
+		option(interpreter)
+	}
+	return interpreter
 }
 
-func (i *Interpreter) Interpret(statements []ast.Stmt) (outerr error) {
-	defer func() {
-		err := recover()
-		if err == nil {
-			return
-		}
-		if runtimeError, ok := err.(*RuntimeError); ok {
-			outerr = runtimeError
-			return
-		}
-		panic(err)
-	}()
+// A global environment holding only the built-in natives.
+func builtins() *Environment {
+	globals := NewEnvironment(nil)
+	globals.Define("clock", Clock)
+	globals.Define("bigint", ToBigInt)
+	globals.Define("decimal", ToDecimal)
+	globals.Define("float", ToFloat)
+	return globals
+}
 
-	for _, statement := range statements {
-		i.execute(statement)
+// Makes a Go function available to Lox code as the global name, as
+// NewNativeFunction describes.
+func (i *Interpreter) DefineNative(name string, arity int, fn func(*Interpreter, []Value) (Value, error)) {
+	i.globals.Define(name, NewNativeFunction(name, arity, fn))
+}
+
+// Defines a global variable, converting value as ToLox does.
+func (i *Interpreter) DefineGlobal(name string, value any) {
+	i.globals.Define(name, ToLox(value))
+}
+
+// Runs the statements until they finish or ctx is cancelled. Cancelling
+// raises a RuntimeError that unwraps to ctx.Err(), and leaves the interpreter
+// ready to run something else.
+func (i *Interpreter) Interpret(ctx context.Context, statements []ast.Stmt) error {
+	_, err := i.interpret(ctx, statements)
+	return err
+}
+
+// Runs the statements, returning the value of the last one if it's an
+// expression statement.
+func (i *Interpreter) interpret(ctx context.Context, statements []ast.Stmt) (value any, outerr error) {
+	defer i.leave(i.enter(ctx), &outerr)
+
+	for n, statement := range statements {
+		i.checkCancelled(statement)
+		if expr, ok := statement.(*ast.ExpressionStmt); ok && n == len(statements)-1 {
+			value = i.evaluate(expr.Expression)
+		} else {
+			i.execute(statement)
+		}
 		i.resetReturnValue()
 	}
-	return nil
+	return value, nil
+}
+
+// What the interpreter was doing when the host asked it to run something,
+// which is put back when that stops with an error. The host can ask from
+// inside a native, so there may be Lox code under way.
+type entry struct {
+	ctx         context.Context
+	environment *Environment
+	tables      *sideTables
+	depth       int
+}
+
+// Starts running code for the host, under ctx.
+func (i *Interpreter) enter(ctx context.Context) entry {
+	entered := entry{ctx: i.ctx, environment: i.environment, tables: i.tables, depth: len(i.frames)}
+	i.ctx = ctx
+	if entered.depth == 0 {
+		i.resetUsage()
+	}
+	return entered
+}
+
+// Finishes running code started with enter, and must be deferred. A runtime
+// error is recovered and stored in err, after the interpreter has been put
+// back as it was.
+func (i *Interpreter) leave(entered entry, err *error) {
+	i.ctx = entered.ctx
+	recovered := recover()
+	if recovered == nil {
+		return
+	}
+	runtimeError, ok := recovered.(*RuntimeError)
+	if !ok {
+		panic(recovered)
+	}
+
+	// Errors passed back through Call already have their trace
+	if runtimeError.stackTrace == nil {
+		runtimeError.stackTrace = i.stackTrace()
+	}
+	i.environment, i.tables = entered.environment, entered.tables
+	i.frames = i.frames[:entered.depth]
+	i.resetReturnValue()
+	i.activeBreak, i.activeContinue = nil, nil
+	*err = runtimeError
 }
 
 func (i *Interpreter) execute(stmt ast.Stmt) {
+	i.step(stmt)
 	stmt.Accept(i)
 }
 
+func (i *Interpreter) VisitBadStmt(stmt *ast.BadStmt) {
+	panic(&RuntimeError{token: stmt.From, message: "Can't run code that failed to parse."})
+}
+
 func (i *Interpreter) VisitBlock(stmt *ast.Block) {
 	i.executeBlock(stmt.Statements, NewEnvironment(i.environment))
 }
@@ -67,15 +179,24 @@ func (i *Interpreter) executeBlock(statements []ast.Stmt, environment *Environme
 	}()
 
 	i.environment = environment
+	// Function bodies are blocks too, so this also stops a chain of tail
+	// calls, which doesn't pass through VisitCall
+	if len(statements) > 0 {
+		i.checkCancelled(statements[0])
+	}
 
 	for _, statement := range statements {
 		i.execute(statement)
-		if i.activeReturn {
+		if i.unwinding() {
 			return
 		}
 	}
 }
 
+func (i *Interpreter) VisitBreak(stmt *ast.Break) {
+	i.activeBreak = i.tables.jumps[stmt]
+}
+
 func (i *Interpreter) VisitClass(stmt *ast.Class) {
 	var superclass *LoxClass
 	if stmt.Superclass != nil {
@@ -84,6 +205,7 @@ func (i *Interpreter) VisitClass(stmt *ast.Class) {
 		if !ok {
 			panic(&RuntimeError{
 				token:   stmt.Superclass.Name,
+				code:    TYPE_ERROR,
 				message: "Superclass must be a class",
 			})
 		}
@@ -99,7 +221,7 @@ func (i *Interpreter) VisitClass(stmt *ast.Class) {
 
 	methods := map[string]*LoxFunction{}
 	for _, method := range stmt.Methods {
-		function := NewLoxFunction(method, i.environment, method.Name.Lexeme == "init")
+		function := NewLoxFunction(method, i.environment, i.tables, method.Name.Lexeme == "init")
 		methods[method.Name.Lexeme] = function
 	}
 
@@ -112,13 +234,17 @@ func (i *Interpreter) VisitClass(stmt *ast.Class) {
 	i.environment.Assign(stmt.Name, class)
 }
 
+func (i *Interpreter) VisitContinue(stmt *ast.Continue) {
+	i.activeContinue = i.tables.jumps[stmt]
+}
+
 func (i *Interpreter) VisitExpressionStmt(stmt *ast.ExpressionStmt) {
 	i.evaluate(stmt.Expression)
 }
 
 func (i *Interpreter) VisitFunction(stmt *ast.Function) {
 	i.environment.Define(stmt.Name.Lexeme, nil)
-	function := NewLoxFunction(stmt, i.environment, false)
+	function := NewLoxFunction(stmt, i.environment, i.tables, false)
 	i.environment.Assign(stmt.Name, function)
 }
 
@@ -130,14 +256,34 @@ func (i *Interpreter) VisitIf(stmt *ast.If) {
 	}
 }
 
+func (i *Interpreter) VisitMatch(stmt *ast.Match) {
+	subject := i.evaluate(stmt.Subject)
+
+	for _, matchCase := range stmt.Cases {
+		environment := NewEnvironment(i.environment)
+		if i.matchCase(matchCase, subject, environment) {
+			i.executeBlock([]ast.Stmt{matchCase.Body}, environment)
+			return
+		}
+	}
+}
+
 func (i *Interpreter) VisitPrint(stmt *ast.Print) {
 	value := i.evaluate(stmt.Expression)
-	fmt.Println(value)
+	fmt.Fprintln(i.stdout, stringify(value))
 }
 
 func (i *Interpreter) VisitReturn(stmt *ast.Return) {
 	var value any
-	if stmt.Value != nil {
+	if call, ok := stmt.Value.(*ast.Call); ok && i.tables.tailCalls[call] {
+		function, arguments := i.evaluateCall(call)
+		if loxFunction, ok := function.(*LoxFunction); ok {
+			i.activeReturn = true
+			i.activeTailCall = &tailCall{function: loxFunction, arguments: arguments, call: call}
+			return
+		}
+		value = i.call(function, arguments, call)
+	} else if stmt.Value != nil {
 		value = i.evaluate(stmt.Value)
 	}
 
@@ -154,16 +300,36 @@ func (i *Interpreter) VisitVar(stmt *ast.Var) {
 	i.environment.Define(stmt.Name.Lexeme, value)
 }
 
+func (i *Interpreter) VisitVarDestructure(stmt *ast.VarDestructure) {
+	value := i.evaluate(stmt.Initializer)
+	if err := i.matchPattern(stmt.Pattern, value); err != nil {
+		panic(err)
+	}
+}
+
 func (i *Interpreter) VisitWhile(stmt *ast.While) {
 	for isTruthy(i.evaluate(stmt.Condition)) {
+		i.checkCancelled(stmt.Keyword)
 		i.execute(stmt.Body)
-		if i.activeReturn {
+		if i.activeBreak == stmt {
+			i.activeBreak = nil
 			return
 		}
+		if i.activeContinue == stmt {
+			i.activeContinue = nil
+		}
+		if i.unwinding() {
+			return
+		}
+
+		if stmt.Increment != nil {
+			i.evaluate(stmt.Increment)
+		}
 	}
 }
 
 func (i *Interpreter) evaluate(expr ast.Expr) any {
+	i.step(expr)
 	return expr.Accept(i)
 }
 
@@ -172,19 +338,30 @@ func (*Interpreter) VisitLiteral(literal *ast.Literal) any {
 }
 
 func (i *Interpreter) VisitGet(get *ast.Get) any {
-	object := i.evaluate(get.Object)
-	if instance, ok := object.(*LoxInstance); ok {
-		value, err := instance.Get(get.Name)
-		if err != nil {
-			panic(err)
+	var value any
+	var err error
+	switch object := i.evaluate(get.Object).(type) {
+	case Object:
+		value, err = object.Get(get.Name)
+	case string:
+		value, err = bindPrimitive(stringMethods, object, get.Name, "string")
+	case int64, float64, *big.Int, *Decimal:
+		value, err = bindPrimitive(numberMethods, object, get.Name, "number")
+	case *LoxList:
+		value, err = bindPrimitive(listMethods, object, get.Name, "list")
+	case *LoxTuple:
+		value, err = bindPrimitive(tupleMethods, object, get.Name, "tuple")
+	default:
+		err = &RuntimeError{
+			token:   get.Name,
+			code:    TYPE_ERROR,
+			message: "Only instances, strings, numbers, lists and tuples have properties.",
 		}
-		return value
 	}
-
-	panic(&RuntimeError{
-		token:   get.Name,
-		message: "Only instances have properties.",
-	})
+	if err != nil {
+		panic(err)
+	}
+	return value
 }
 
 func (i *Interpreter) VisitGrouping(grouping *ast.Grouping) any {
@@ -194,16 +371,19 @@ func (i *Interpreter) VisitGrouping(grouping *ast.Grouping) any {
 func (i *Interpreter) VisitSet(set *ast.Set) any {
 	object := i.evaluate(set.Object)
 
-	instance, ok := object.(*LoxInstance)
+	instance, ok := object.(Object)
 	if !ok {
 		panic(&RuntimeError{
 			token:   set.Name,
+			code:    TYPE_ERROR,
 			message: "Only instances have fields.",
 		})
 	}
 
 	value := i.evaluate(set.Value)
-	instance.Set(set.Name, value)
+	if err := instance.Set(set.Name, value); err != nil {
+		panic(err)
+	}
 	return value
 }
 
@@ -216,6 +396,14 @@ func (i *Interpreter) VisitThis(expr *ast.This) any {
 
 }
 
+func (i *Interpreter) VisitTuple(tuple *ast.Tuple) any {
+	elements := []any{}
+	for _, element := range tuple.Elements {
+		elements = append(elements, i.evaluate(element))
+	}
+	return NewLoxTuple(elements)
+}
+
 func (i *Interpreter) VisitUnary(unary *ast.Unary) any {
 	right := i.evaluate(unary.Right)
 
@@ -224,7 +412,7 @@ func (i *Interpreter) VisitUnary(unary *ast.Unary) any {
 		return !isTruthy(right)
 	case token.MINUS:
 		r := checkNumberOperand(unary.Operator, right)
-		return -r
+		return negate(unary.Operator, r)
 	}
 
 	// Should be unreachable
@@ -236,40 +424,32 @@ func (i *Interpreter) VisitBinary(binary *ast.Binary) any {
 	right := i.evaluate(binary.Right)
 
 	switch binary.Operator.TokenType {
-	case token.MINUS:
-		l, r := checkNumberOperands(binary.Operator, left, right)
-		return l - r
-	case token.SLASH:
-		l, r := checkNumberOperands(binary.Operator, left, right)
-		return l / r
-	case token.STAR:
-		l, r := checkNumberOperands(binary.Operator, left, right)
-		return l * r
+	case token.MINUS, token.SLASH, token.STAR:
+		return arithmetic(binary.Operator, left, right)
 	case token.PLUS:
 		switch l := left.(type) {
-		case float64:
-			r := checkNumberOperand(binary.Operator, right)
-			return l + r
+		case int64, float64, *big.Int, *Decimal:
+			if !isNumber(right) {
+				panic(&RuntimeError{code: TYPE_ERROR, token: binary.Operator, message: "Operands must be numbers or strings"})
+			}
+			return arithmetic(binary.Operator, l, right)
 		case string:
 			if rightStr, ok := right.(string); ok {
+				i.allocateString(binary.Operator, len(l)+len(rightStr))
 				return l + rightStr
 			}
-			panic(&RuntimeError{token: binary.Operator, message: "Operands must be numbers or strings"})
+			panic(&RuntimeError{code: TYPE_ERROR, token: binary.Operator, message: "Operands must be numbers or strings"})
 		default:
-			panic(&RuntimeError{token: binary.Operator, message: "Operands must be numbers or strings"})
+			panic(&RuntimeError{code: TYPE_ERROR, token: binary.Operator, message: "Operands must be numbers or strings"})
 		}
 	case token.GREATER:
-		l, r := checkNumberOperands(binary.Operator, left, right)
-		return l > r
+		return compareNumbers(binary.Operator, left, right) > 0
 	case token.GREATER_EQUAL:
-		l, r := checkNumberOperands(binary.Operator, left, right)
-		return l >= r
+		return compareNumbers(binary.Operator, left, right) >= 0
 	case token.LESS:
-		l, r := checkNumberOperands(binary.Operator, left, right)
-		return l < r
+		return compareNumbers(binary.Operator, left, right) < 0
 	case token.LESS_EQUAL:
-		l, r := checkNumberOperands(binary.Operator, left, right)
-		return l <= r
+		return compareNumbers(binary.Operator, left, right) <= 0
 	case token.BANG_EQUAL:
 		return !isEqual(left, right)
 	case token.EQUAL_EQUAL:
@@ -288,14 +468,18 @@ func (i *Interpreter) VisitVariable(expr *ast.Variable) any {
 	return value
 }
 
+func (i *Interpreter) VisitBadExpr(expr *ast.BadExpr) any {
+	panic(&RuntimeError{token: expr.From, message: "Can't run code that failed to parse."})
+}
+
 func (i *Interpreter) VisitAssign(expr *ast.Assign) any {
 	value := i.evaluate(expr.Value)
-	if distance, ok := i.locals[expr]; ok {
+	if distance, ok := i.tables.locals[expr]; ok {
 		i.environment.AssignAt(distance, expr.Name, value)
 	} else {
 		err := i.globals.Assign(expr.Name, value)
 		if err != nil {
-			panic(err)
+			panic(i.environment.undefinedVariable(expr.Name))
 		}
 	}
 	return value
@@ -318,6 +502,48 @@ func (i *Interpreter) VisitLogical(expr *ast.Logical) any {
 }
 
 func (i *Interpreter) VisitCall(expr *ast.Call) any {
+	function, arguments := i.evaluateCall(expr)
+	i.checkCancelled(expr.Paren)
+	return i.call(function, arguments, expr)
+}
+
+// Natives report errors without knowing where they were called from, so
+// those are given the location of the call here.
+func (i *Interpreter) call(function Callable, arguments []any, call *ast.Call) any {
+	_, native := function.(*NativeFunction)
+	_, class := function.(*LoxClass)
+	// Natives, and classes going over the instance limit, don't know where
+	// they were called from
+	if native || class {
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			if runtimeError, ok := recovered.(*RuntimeError); ok && runtimeError.token == nil && call != nil {
+				runtimeError.token = call.Paren
+			}
+			panic(recovered)
+		}()
+	}
+
+	i.checkCallDepth(call)
+	i.pushFrame(function, call)
+	result := function.Call(i, arguments)
+	i.popFrame()
+	// Lox functions can only return strings that were already counted, but
+	// natives, including the methods on strings, make new ones
+	if s, ok := result.(string); ok && native {
+		var at *token.Token
+		if call != nil {
+			at = call.Paren
+		}
+		i.allocateString(at, len(s))
+	}
+	return result
+}
+
+func (i *Interpreter) evaluateCall(expr *ast.Call) (Callable, []any) {
 	callee := i.evaluate(expr.Callee)
 
 	arguments := []any{}
@@ -327,16 +553,16 @@ func (i *Interpreter) VisitCall(expr *ast.Call) any {
 
 	function, ok := callee.(Callable)
 	if !ok {
-		panic(&RuntimeError{token: expr.Paren, message: "Can only call functions and classes."})
+		panic(&RuntimeError{code: TYPE_ERROR, token: expr.Paren, message: "Can only call functions and classes."})
 	}
 	if len(arguments) != function.Arity() {
-		panic(&RuntimeError{token: expr.Paren, message: fmt.Sprintf("Expected %d arguments but got %d.", function.Arity(), len(arguments))})
+		panic(&RuntimeError{code: ARITY_ERROR, token: expr.Paren, message: fmt.Sprintf("Expected %d arguments but got %d.", function.Arity(), len(arguments))})
 	}
-	return function.Call(i, arguments)
+	return function, arguments
 }
 
 func (i *Interpreter) VisitSuper(expr *ast.Super) any {
-	distance := i.locals[expr]
+	distance := i.tables.locals[expr]
 	superclassObj, err := i.environment.GetAt(distance, "super")
 	if err != nil {
 		panic(err)
@@ -353,6 +579,7 @@ func (i *Interpreter) VisitSuper(expr *ast.Super) any {
 	if method == nil {
 		panic(&RuntimeError{
 			token:   expr.Method,
+			code:    UNDEFINED_PROPERTY_ERROR,
 			message: fmt.Sprintf("Undefined property '%s'.", expr.Method.Lexeme),
 		})
 	}
@@ -360,20 +587,42 @@ func (i *Interpreter) VisitSuper(expr *ast.Super) any {
 	return method.Bind(object)
 }
 
+// Aborts the program if the context it's running under has been cancelled.
+// Waiting on Done this way is cheap enough to do on every loop and call.
+// The error is reported at, which is a token or a node, as positionOf
+// describes. Finding where a node is takes time, so it's only done once the
+// context has been cancelled.
+func (i *Interpreter) checkCancelled(at any) {
+	select {
+	case <-i.ctx.Done():
+		err := i.ctx.Err()
+		panic(&RuntimeError{code: INTERRUPTED_ERROR, token: positionOf(at), message: fmt.Sprintf("Interrupted: %s.", err), cause: err})
+	default:
+	}
+}
+
 func (i *Interpreter) resetReturnValue() {
 	i.activeReturn = false
 	i.activeReturnValue = nil
+	i.activeTailCall = nil
 }
 
-func (i *Interpreter) resolve(expr ast.Expr, depth int) {
-	i.locals[expr] = depth
+// A break or continue unwinds every statement up to the loop it targets, in
+// the same way that a return unwinds up to the function call.
+func (i *Interpreter) unwinding() bool {
+	return i.activeReturn || i.activeBreak != nil || i.activeContinue != nil
 }
 
 func (i *Interpreter) lookUpVariable(name *token.Token, expr ast.Expr) (any, error) {
-	if distance, ok := i.locals[expr]; ok {
+	if distance, ok := i.tables.locals[expr]; ok {
 		return i.environment.GetAt(distance, name.Lexeme)
 	} else {
-		return i.globals.Get(name)
+		value, err := i.globals.Get(name)
+		if err != nil {
+			// Locals are worth suggesting too
+			return nil, i.environment.undefinedVariable(name)
+		}
+		return value, nil
 	}
 }
 
@@ -387,24 +636,48 @@ func isTruthy(object any) bool {
 	return true
 }
 
-// This should be sufficient if I understand how Go equality is implemented
+func stringify(object any) string {
+	switch object := object.(type) {
+	case nil:
+		return "nil"
+	case float64:
+		return formatFloat(object)
+	}
+	return fmt.Sprint(object)
+}
+
+// This should be sufficient if I understand how Go equality is implemented,
+// apart from numbers of different kinds and tuples which are compared
+// element by element
 func isEqual(left any, right any) bool {
+	if isNumber(left) && isNumber(right) {
+		return numbersEqual(left, right)
+	}
+	if leftTuple, ok := left.(*LoxTuple); ok {
+		rightTuple, ok := right.(*LoxTuple)
+		return ok && leftTuple.Equal(rightTuple)
+	}
 	return left == right
 }
 
-func checkNumberOperand(operator *token.Token, operand any) float64 {
-	numberOperand, ok := operand.(float64)
-	if !ok {
-		panic(&RuntimeError{token: operator, message: "Operand must be a number"})
+func checkNumberOperand(operator *token.Token, operand any) any {
+	if !isNumber(operand) {
+		panic(&RuntimeError{code: TYPE_ERROR, token: operator, message: "Operand must be a number"})
 	}
-	return numberOperand
+	return operand
 }
 
-func checkNumberOperands(operator *token.Token, left any, right any) (float64, float64) {
-	leftNumber, ok := left.(float64)
-	rightNumber, ok2 := right.(float64)
-	if !ok || !ok2 {
-		panic(&RuntimeError{token: operator, message: "Operands must be numbers"})
+// Promotes both operands to the same kind of number, following the rules in
+// numeric.go.
+func checkNumberOperands(operator *token.Token, left any, right any) (any, any) {
+	leftKind, rightKind := kindOf(left), kindOf(right)
+	if leftKind == NOT_A_NUMBER || rightKind == NOT_A_NUMBER {
+		panic(&RuntimeError{code: TYPE_ERROR, token: operator, message: "Operands must be numbers"})
+	}
+
+	kind := max(leftKind, rightKind)
+	if kind == FLOAT && min(leftKind, rightKind) > INTEGER && leftKind != rightKind {
+		panic(&RuntimeError{code: TYPE_ERROR, token: operator, message: "Can't mix a float with a big integer or decimal; convert one with float(), bigint() or decimal()"})
 	}
-	return leftNumber, rightNumber
+	return promote(left, kind), promote(right, kind)
 }
@@ -0,0 +1,585 @@
+package interpreter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func global(t *testing.T, interpreter *Interpreter, name string) any {
+	value, err := interpreter.globals.Get(tokenNamed(name))
+	require.NoError(t, err)
+	return value
+}
+
+func TestTailRecursionRunsInConstantStack(t *testing.T) {
+	interpreter := NewInterpreter()
+	err := run(`
+fun count(n, acc) {
+  if (n == 0) return acc;
+  return count(n - 1, acc + 1);
+}
+var result = count(1000000, 0);
+`, interpreter)
+	require.NoError(t, err)
+	require.Equal(t, int64(1000000), global(t, interpreter, "result"))
+}
+
+func TestMutualTailCalls(t *testing.T) {
+	interpreter := NewInterpreter()
+	err := run(`
+fun isEven(n) {
+  if (n == 0) return true;
+  return isOdd(n - 1);
+}
+fun isOdd(n) {
+  if (n == 0) return false;
+  return isEven(n - 1);
+}
+var result = isEven(100001);
+`, interpreter)
+	require.NoError(t, err)
+	require.Equal(t, false, global(t, interpreter, "result"))
+}
+
+func TestTailCallToClassAndNative(t *testing.T) {
+	interpreter := NewInterpreter()
+	err := run(`
+class Box {
+  init(value) { this.value = value; }
+}
+fun box(value) { return Box(value); }
+fun now() { return clock(); }
+var result = box(3).value;
+var time = now();
+`, interpreter)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), global(t, interpreter, "result"))
+	require.IsType(t, float64(0), global(t, interpreter, "time"))
+}
+
+func TestTailCallFromMethod(t *testing.T) {
+	interpreter := NewInterpreter()
+	err := run(`
+class Counter {
+  down(n) {
+    if (n == 0) return this;
+    return this.down(n - 1);
+  }
+}
+var counter = Counter();
+var result = counter.down(100000) == counter;
+`, interpreter)
+	require.NoError(t, err)
+	require.Equal(t, true, global(t, interpreter, "result"))
+}
+
+func TestStringMethods(t *testing.T) {
+	interpreter := NewInterpreter()
+	err := run(`
+var s = "  a,b,c  ".trim();
+var length = s.length();
+var parts = s.split(",");
+var second = parts.get(1);
+var count = parts.length();
+var sub = "hello".substring(1, 3);
+var index = "hello".indexOf("l");
+var shout = "hi".upper() + "HI".lower();
+var swapped = "a-b-a".replace("a", "c");
+var starts = "golox".startsWith("go");
+var chars = "abc".chars();
+`, interpreter)
+	require.NoError(t, err)
+	require.Equal(t, int64(5), global(t, interpreter, "length"))
+	require.Equal(t, "b", global(t, interpreter, "second"))
+	require.Equal(t, int64(3), global(t, interpreter, "count"))
+	require.Equal(t, "el", global(t, interpreter, "sub"))
+	require.Equal(t, int64(2), global(t, interpreter, "index"))
+	require.Equal(t, "HIhi", global(t, interpreter, "shout"))
+	require.Equal(t, "c-b-c", global(t, interpreter, "swapped"))
+	require.Equal(t, true, global(t, interpreter, "starts"))
+	require.Equal(t, "[a, b, c]", stringify(global(t, interpreter, "chars")))
+}
+
+func TestNumberMethods(t *testing.T) {
+	interpreter := NewInterpreter()
+	err := run(`
+var floor = (2.7).floor();
+var round = (2.5).round();
+var fixed = (3.14159).toFixed(2);
+var whole = (4).isInteger();
+var fraction = (4.5).isInteger();
+`, interpreter)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), global(t, interpreter, "floor"))
+	require.Equal(t, int64(3), global(t, interpreter, "round"))
+	require.Equal(t, "3.14", global(t, interpreter, "fixed"))
+	require.Equal(t, true, global(t, interpreter, "whole"))
+	require.Equal(t, false, global(t, interpreter, "fraction"))
+}
+
+func TestPrimitiveMethodErrors(t *testing.T) {
+	err := run(`"abc".reverse();`, NewInterpreter())
+	require.ErrorContains(t, err, "Undefined string method 'reverse'.")
+
+	err = run(`"abc".substring(2, 10);`, NewInterpreter())
+	require.ErrorContains(t, err, "out of bounds")
+
+	err = run(`"abc".split(1);`, NewInterpreter())
+	require.ErrorContains(t, err, "Argument 1 to 'split' must be a string.")
+
+	err = run(`nil.length();`, NewInterpreter())
+	require.ErrorContains(t, err, "have properties")
+}
+
+func TestBreakAndContinue(t *testing.T) {
+	interpreter := NewInterpreter()
+	err := run(`
+var evens = 0;
+for (var i = 0; i < 100; i = i + 1) {
+  if (i == 10) break;
+  if (i - (i / 2).floor() * 2 == 1) continue;
+  evens = evens + 1;
+}
+`, interpreter)
+	require.NoError(t, err)
+	require.Equal(t, int64(5), global(t, interpreter, "evens"))
+}
+
+func TestLabeledLoops(t *testing.T) {
+	interpreter := NewInterpreter()
+	err := run(`
+var pairs = 0;
+var found;
+outer: for (var i = 0; i < 5; i = i + 1) {
+  var j = 0;
+  inner: while (true) {
+    j = j + 1;
+    if (j > i) continue outer;
+    pairs = pairs + 1;
+    if (i * j == 6) {
+      found = i;
+      break outer;
+    }
+  }
+}
+
+fun firstOver(limit) {
+  loop: while (true) {
+    limit = limit + 1;
+    if (limit > 3) return limit;
+    continue loop;
+  }
+}
+var over = firstOver(0);
+`, interpreter)
+	require.NoError(t, err)
+	require.Equal(t, int64(5), global(t, interpreter, "pairs"))
+	require.Equal(t, int64(3), global(t, interpreter, "found"))
+	require.Equal(t, int64(4), global(t, interpreter, "over"))
+}
+
+func TestLoopLabelErrors(t *testing.T) {
+	err := run(`break;`, NewInterpreter())
+	require.ErrorContains(t, err, "Can't use 'break' outside of a loop")
+
+	err = run(`while (true) { break missing; }`, NewInterpreter())
+	require.ErrorContains(t, err, "No enclosing loop labeled 'missing'")
+
+	err = run(`a: while (true) { a: while (true) {} }`, NewInterpreter())
+	require.ErrorContains(t, err, "Label 'a' is already used by an enclosing loop")
+
+	err = run(`while (true) { fun f() { continue; } }`, NewInterpreter())
+	require.ErrorContains(t, err, "Can't use 'continue' outside of a loop")
+
+	err = run(`a: print 1;`, NewInterpreter())
+	require.ErrorContains(t, err, "Expect loop after label.")
+}
+
+func TestMatchStatement(t *testing.T) {
+	interpreter := NewInterpreter()
+	err := run(`
+class Point {
+  init(x, y) { this.x = x; this.y = y; }
+}
+class Point3 < Point {
+  init(x, y, z) { super.init(x, y); this.z = z; }
+}
+
+fun describe(value) {
+  var result;
+  match (value) {
+    case 1, 2 => result = "small";
+    case -1 => result = "negative one";
+    case "hi" => result = "greeting";
+    case [a, b] => result = a + b;
+    case Point{x: 0, y} => result = "on y axis at " + y;
+    case Point{x, y} if x == y => result = "diagonal";
+    case Point{x, y} => result = x * y;
+    case n if n == 200 => result = "big";
+    case _ => result = "other";
+  }
+  return result;
+}
+
+var one = describe(2);
+var negative = describe(-1);
+var greeting = describe("hi");
+var joined = describe("a,b".split(","));
+var axis = describe(Point(0, "5"));
+var diagonal = describe(Point3(4, 4, 1));
+var product = describe(Point(3, 4));
+var big = describe(200);
+var other = describe(50);
+var long = describe("a,b,c".split(","));
+`, interpreter)
+	require.NoError(t, err)
+	require.Equal(t, "small", global(t, interpreter, "one"))
+	require.Equal(t, "negative one", global(t, interpreter, "negative"))
+	require.Equal(t, "greeting", global(t, interpreter, "greeting"))
+	require.Equal(t, "ab", global(t, interpreter, "joined"))
+	require.Equal(t, "on y axis at 5", global(t, interpreter, "axis"))
+	require.Equal(t, "diagonal", global(t, interpreter, "diagonal"))
+	require.Equal(t, int64(12), global(t, interpreter, "product"))
+	require.Equal(t, "big", global(t, interpreter, "big"))
+	require.Equal(t, "other", global(t, interpreter, "other"))
+	require.Equal(t, "other", global(t, interpreter, "long"))
+}
+
+func TestMatchBindingsAreScopedToTheCase(t *testing.T) {
+	interpreter := NewInterpreter()
+	err := run(`
+var x = "outer";
+var seen;
+match (1) {
+  case x => seen = x;
+}
+`, interpreter)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), global(t, interpreter, "seen"))
+	require.Equal(t, "outer", global(t, interpreter, "x"))
+}
+
+func TestMatchErrors(t *testing.T) {
+	err := run(`match (1) { case 1, x => print x; }`, NewInterpreter())
+	require.ErrorContains(t, err, "Alternative patterns can't bind variables")
+
+	err = run(`match (1) { case [x, x] => print x; }`, NewInterpreter())
+	require.ErrorContains(t, err, "Already a variable with this name in this scope")
+
+	err = run(`var notClass = 1; match (1) { case notClass{} => print 1; }`, NewInterpreter())
+	require.ErrorContains(t, err, "'notClass' in pattern is not a class.")
+
+	err = run(`match (1) { case 1 print 1; }`, NewInterpreter())
+	require.ErrorContains(t, err, "Expect '=>' after case pattern.")
+}
+
+func TestTupleReturnAndDestructuring(t *testing.T) {
+	interpreter := NewInterpreter()
+	err := run(`
+fun divmod(a, b) {
+  var q = (a / b).floor();
+  return q, a - q * b;
+}
+var (q, r) = divmod(17, 5);
+var pair = divmod(9, 2);
+var same = pair == divmod(9, 2);
+var second = pair.get(1);
+
+var [first, ...rest] = "a,b,c".split(",");
+var [only, ..._] = "x".split(",");
+fun nestedPair() { return 2, 3; }
+var sum;
+{
+  fun pairOfPairs() { return 1, nestedPair(); }
+  var (a, (b, c)) = pairOfPairs();
+  sum = a + b + c;
+}
+`, interpreter)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), global(t, interpreter, "q"))
+	require.Equal(t, int64(2), global(t, interpreter, "r"))
+	require.Equal(t, "(4, 1)", stringify(global(t, interpreter, "pair")))
+	require.Equal(t, true, global(t, interpreter, "same"))
+	require.Equal(t, "a", global(t, interpreter, "first"))
+	require.Equal(t, "[b, c]", stringify(global(t, interpreter, "rest")))
+	require.Equal(t, "x", global(t, interpreter, "only"))
+	require.Equal(t, int64(1), global(t, interpreter, "second"))
+	require.Equal(t, int64(6), global(t, interpreter, "sum"))
+}
+
+func TestDestructuringArityErrors(t *testing.T) {
+	err := run(`fun f() { return 1, 2, 3; } var (a, b) = f();`, NewInterpreter())
+	require.ErrorContains(t, err, "Expected 2 elements but got 3.")
+
+	err = run(`var [a, b, ...c] = "x".split(",");`, NewInterpreter())
+	require.ErrorContains(t, err, "Expected at least 2 elements but got 1.")
+
+	err = run(`var (a, b) = 1;`, NewInterpreter())
+	require.ErrorContains(t, err, "Expected a tuple but got 1.")
+
+	err = run(`var [...a, b] = "x".split(",");`, NewInterpreter())
+	require.ErrorContains(t, err, "Rest pattern must be last.")
+
+	err = run(`var (a, b);`, NewInterpreter())
+	require.ErrorContains(t, err, "Expect '=' after destructuring pattern.")
+}
+
+func TestIntegerAndFloatArithmetic(t *testing.T) {
+	interpreter := NewInterpreter()
+	err := run(`
+var big = 9007199254740993;
+var next = big + 1;
+var mixed = 1 + 0.5;
+var exact = 6 / 3;
+var inexact = 7 / 2;
+var product = -4 * 3;
+var equal = 3 == 3.0;
+var unequal = big == 9007199254740992.0;
+var greater = big > 9007199254740992.0;
+var notLess = 9007199254740992.0 >= big;
+var less = 2 < 2.5;
+var lessThanInfinity = big < 1.0 / 0.0;
+var floats = 1.5 / 0.5 - 0.25;
+var negative = -big;
+`, interpreter)
+	require.NoError(t, err)
+	require.Equal(t, int64(9007199254740994), global(t, interpreter, "next"))
+	require.Equal(t, 1.5, global(t, interpreter, "mixed"))
+	require.Equal(t, int64(2), global(t, interpreter, "exact"))
+	require.Equal(t, 3.5, global(t, interpreter, "inexact"))
+	require.Equal(t, int64(-12), global(t, interpreter, "product"))
+	require.Equal(t, true, global(t, interpreter, "equal"))
+	require.Equal(t, false, global(t, interpreter, "unequal"))
+	require.Equal(t, true, global(t, interpreter, "greater"))
+	require.Equal(t, false, global(t, interpreter, "notLess"))
+	require.Equal(t, true, global(t, interpreter, "less"))
+	require.Equal(t, true, global(t, interpreter, "lessThanInfinity"))
+	require.Equal(t, 2.75, global(t, interpreter, "floats"))
+	require.Equal(t, int64(-9007199254740993), global(t, interpreter, "negative"))
+	require.Equal(t, "3", stringify(int64(3)))
+	require.Equal(t, stringify(int64(3)), stringify(float64(3)))
+	require.Equal(t, "1000000", stringify(float64(1e6)))
+	require.Equal(t, "0.1", stringify(0.1))
+	require.Equal(t, "1e+21", stringify(1e21))
+}
+
+func TestIntegerOverflowIsAnError(t *testing.T) {
+	err := run(`var x = 9223372036854775807 + 1;`, NewInterpreter())
+	require.ErrorContains(t, err, "Integer overflow.")
+
+	err = run(`var x = 3037000500 * 3037000500;`, NewInterpreter())
+	require.ErrorContains(t, err, "Integer overflow.")
+
+	err = run(`var x = -9223372036854775807 - 2;`, NewInterpreter())
+	require.ErrorContains(t, err, "Integer overflow.")
+}
+
+func TestBigIntegerAndDecimalArithmetic(t *testing.T) {
+	interpreter := NewInterpreter()
+	err := run(`
+var huge = 9223372036854775807n + 1;
+var product = 99999999999999999999n * 10;
+var exactQuotient = 10n / 5n;
+var inexactQuotient = 1n / 4n;
+var total = 1.10d + 2.205d;
+var price = 19.99d * 3;
+var third = 1d / 3d;
+var quarter = 1.00d / 4;
+var negative = -1.50d;
+var bigger = 2.5d > 2n;
+var equal = 1n == 1.00d;
+var equalFloat = 0.5d == 0.5;
+var fromString = bigint("12345678901234567890");
+var fromFloat = decimal(0.1);
+var backToFloat = float(1.25d);
+var fixed = (2.345d).toFixed(2);
+var floored = (-2.5d).floor();
+`, interpreter)
+	require.NoError(t, err)
+	for name, expected := range map[string]string{
+		"huge":            "9223372036854775808",
+		"product":         "999999999999999999990",
+		"exactQuotient":   "2",
+		"inexactQuotient": "0.25",
+		"total":           "3.305",
+		"price":           "59.97",
+		"third":           "0.333333333333333333",
+		"quarter":         "0.25",
+		"negative":        "-1.50",
+		"bigger":          "true",
+		"equal":           "true",
+		"equalFloat":      "true",
+		"fromString":      "12345678901234567890",
+		"fromFloat":       "0.1",
+		"backToFloat":     "1.25",
+		"fixed":           "2.35",
+		"floored":         "-3",
+	} {
+		require.Equal(t, expected, stringify(global(t, interpreter, name)), name)
+	}
+}
+
+func TestMixingFloatsWithExactNumbersIsAnError(t *testing.T) {
+	err := run(`var x = 1.5 + 1n;`, NewInterpreter())
+	require.ErrorContains(t, err, "Can't mix a float with a big integer or decimal")
+
+	err = run(`var x = 1.10d < 2.0;`, NewInterpreter())
+	require.ErrorContains(t, err, "Can't mix a float with a big integer or decimal")
+
+	err = run(`var x = 1d / 0;`, NewInterpreter())
+	require.ErrorContains(t, err, "Division by zero.")
+
+	err = run(`
+var x = bigint(1.5);`, NewInterpreter())
+	require.ErrorContains(t, err, "Runtime error line 2: Can't convert 1.5 to a big integer without losing its fraction.")
+}
+
+func TestStringMethodsCountCodePoints(t *testing.T) {
+	interpreter := NewInterpreter()
+	err := run(`
+var word = "日本語のテキスト";
+var length = word.length();
+var sub = word.substring(1, 3);
+var index = word.indexOf("テ");
+var chars = "añb".chars();
+var upper = "ñandú".upper();
+`, interpreter)
+	require.NoError(t, err)
+	require.Equal(t, int64(8), global(t, interpreter, "length"))
+	require.Equal(t, "本語", global(t, interpreter, "sub"))
+	require.Equal(t, int64(4), global(t, interpreter, "index"))
+	require.Equal(t, "[a, ñ, b]", stringify(global(t, interpreter, "chars")))
+	require.Equal(t, "ÑANDÚ", global(t, interpreter, "upper"))
+}
+
+func TestRuntimeErrorsCarryStackTrace(t *testing.T) {
+	interpreter := NewInterpreter()
+	err := run(`
+class Parser {
+  init(text) { this.n = text.length(); this.text = text; }
+  parse() { return this.text.substring(0, this.n + 1); }
+}
+fun parse(text) {
+  var parser = Parser(text);
+  print parser.parse();
+}
+parse("abc");
+`, interpreter)
+	require.Error(t, err)
+
+	trace := err.(*RuntimeError).StackTrace()
+	functions := []string{}
+	for _, frame := range trace {
+		functions = append(functions, frame.Function)
+	}
+	require.Equal(t, []string{"string.substring", "parse", "parse"}, functions)
+	require.Equal(t, "in string.substring, called at 4:20", trace[0].String())
+	require.Equal(t, "in parse, called at 10:1", trace[2].String())
+
+	// The stack is cleared, so later errors don't inherit it
+	err = run(`print 1 + nil;`, interpreter)
+	require.Error(t, err)
+	require.Empty(t, err.(*RuntimeError).StackTrace())
+}
+
+func TestStackTraceCountsElidedTailCalls(t *testing.T) {
+	err := run(`
+fun down(n) {
+  if (n == 0) return nil + 1;
+  return down(n - 1);
+}
+down(3);
+`, NewInterpreter())
+	require.Error(t, err)
+
+	trace := err.(*RuntimeError).StackTrace()
+	require.Len(t, trace, 1)
+	require.Equal(t, Frame{Function: "down", CallSite: trace[0].CallSite, ElidedTailCalls: 3}, trace[0])
+	require.Equal(t, "in down, called at 4:10 (after 3 tail calls)", trace[0].String())
+}
+
+func TestUndefinedNamesSuggestNearMatches(t *testing.T) {
+	err := run(`
+class Shape { area() { return 0; } }
+class Square < Shape { init(side) { this.side = side; } }
+var square = Square(2);
+print square.aera();
+`, NewInterpreter())
+	require.EqualError(t, err, "Runtime error line 5: Undefined property 'aera'. Did you mean 'area'?")
+
+	err = run(`
+class Square { init(side) { this.side = side; } }
+print Square(2).sdie;
+`, NewInterpreter())
+	require.EqualError(t, err, "Runtime error line 3: Undefined property 'sdie'. Did you mean 'side'?")
+
+	err = run(`
+fun area(width) {
+  var height = 2;
+  return width * hieght;
+}
+print area(3);
+`, NewInterpreter())
+	require.EqualError(t, err, "Runtime error line 4: Undefined variable 'hieght'. Did you mean 'height'?")
+
+	err = run(`{ var total = 1; totl = 2; }`, NewInterpreter())
+	require.EqualError(t, err, "Runtime error line 1: Undefined variable 'totl'. Did you mean 'total'?")
+
+	err = run(`print "abc".lenght();`, NewInterpreter())
+	require.EqualError(t, err, "Runtime error line 1: Undefined string method 'lenght'. Did you mean 'length'?")
+}
+
+func TestDefineNative(t *testing.T) {
+	interpreter := NewInterpreter()
+	interpreter.DefineGlobal("greeting", "hello")
+	interpreter.DefineNative("shout", 1, func(interpreter *Interpreter, arguments []Value) (Value, error) {
+		text, ok := arguments[0].(string)
+		if !ok {
+			return nil, fmt.Errorf("can't shout %v", arguments[0])
+		}
+		return strings.ToUpper(text) + "!", nil
+	})
+
+	value, err := interpreter.Eval(context.Background(), "shout(greeting);")
+	require.NoError(t, err)
+	require.Equal(t, "HELLO!", value)
+
+	_, err = interpreter.Eval(context.Background(), "\nshout(1);")
+	var runtimeError *RuntimeError
+	require.ErrorAs(t, err, &runtimeError)
+	require.Equal(t, "can't shout 1", runtimeError.Message())
+	require.Equal(t, 2, runtimeError.Span().Start.Line)
+	require.Equal(t, "in shout, called at 2:1", runtimeError.StackTrace()[0].String())
+}
+
+func TestNativeErrorsUnwrap(t *testing.T) {
+	sentinel := errors.New("out of stock")
+	interpreter := NewInterpreter()
+	interpreter.DefineNative("order", 0, func(*Interpreter, []Value) (Value, error) {
+		return nil, sentinel
+	})
+
+	err := interpreter.Exec("order();")
+	require.ErrorIs(t, err, sentinel)
+}
+
+func TestSelfContainingListsPrint(t *testing.T) {
+	list := NewLoxList([]any{"a"})
+	tuple := NewLoxTuple([]any{int64(1), list})
+	list.elements = append(list.elements, list, tuple)
+
+	require.Equal(t, "[a, [...], (1, [...])]", stringify(list))
+	require.Equal(t, "(1, [a, [...], (...)])", stringify(tuple))
+	require.True(t, isEqual(list, list))
+	require.True(t, isEqual(tuple, NewLoxTuple([]any{int64(1), list})))
+}
+
+func TestListsHaveNoMutators(t *testing.T) {
+	err := run(`var l = "a".split(","); l.push(l);`, NewInterpreter())
+	require.ErrorContains(t, err, "Undefined list method 'push'.")
+}
@@ -0,0 +1,145 @@
+package interpreter
+
+import (
+	"fmt"
+	"runtime"
+	"sync/atomic"
+	"time"
+
+	"github.com/DanielleB-R/golox/interpreter/ast"
+	"github.com/DanielleB-R/golox/interpreter/token"
+)
+
+// Deep enough for any reasonable recursion, but shallow enough that the Go
+// stack can't overflow, which would take down the whole process.
+const DEFAULT_MAX_CALL_DEPTH = 10_000
+
+// Limits on the resources a program can use, for running code that isn't
+// trusted. A limit of zero means there is no limit, except for MaxCallDepth,
+// which is DEFAULT_MAX_CALL_DEPTH when it is zero. Steps and string bytes are
+// counted afresh for each run.
+type Limits struct {
+	// Statements and expressions executed
+	MaxSteps int64
+	// Calls in progress at once. Tail calls don't count.
+	MaxCallDepth int
+	// Instances that are still reachable. Before a program is stopped for
+	// going over the limit, garbage is collected so that instances it has
+	// dropped don't count.
+	MaxInstances int64
+	// Bytes in the strings made by concatenation or returned from natives,
+	// including the methods on strings
+	MaxStringBytes int64
+}
+
+// Applies limits to the programs the interpreter runs.
+func WithLimits(limits Limits) Option {
+	return func(i *Interpreter) {
+		i.limits = limits
+		if i.limits.MaxCallDepth == 0 {
+			i.limits.MaxCallDepth = DEFAULT_MAX_CALL_DEPTH
+		}
+	}
+}
+
+// A LimitError says which limit a program went over. It is raised as the
+// cause of a RuntimeError, so that the error still says where the program
+// was.
+type LimitError struct {
+	Limit string
+	Max   int64
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("Exceeded the %s limit of %d.", e.Limit, e.Max)
+}
+
+func (i *Interpreter) exceeded(at *token.Token, limit string, maximum int64) {
+	err := &LimitError{Limit: limit, Max: maximum}
+	panic(&RuntimeError{token: at, code: LIMIT_ERROR, message: err.Error(), cause: err})
+}
+
+// Steps and string bytes are counted for each run rather than for the life of
+// the interpreter.
+func (i *Interpreter) resetUsage() {
+	i.steps = 0
+	i.stringBytes = 0
+}
+
+// Counts node, a statement or expression about to be executed.
+func (i *Interpreter) step(node any) {
+	i.steps++
+	if i.limits.MaxSteps > 0 && i.steps > i.limits.MaxSteps {
+		i.exceeded(positionOf(node), "step", i.limits.MaxSteps)
+	}
+}
+
+func (i *Interpreter) checkCallDepth(call *ast.Call) {
+	if len(i.frames) < i.limits.MaxCallDepth {
+		return
+	}
+	var at *token.Token
+	if call != nil {
+		at = call.Paren
+	}
+	i.exceeded(at, "call depth", int64(i.limits.MaxCallDepth))
+}
+
+// Counts the bytes in a string that is about to be made.
+func (i *Interpreter) allocateString(at *token.Token, bytes int) {
+	i.stringBytes += int64(bytes)
+	if i.limits.MaxStringBytes > 0 && i.stringBytes > i.limits.MaxStringBytes {
+		i.exceeded(at, "string byte", i.limits.MaxStringBytes)
+	}
+}
+
+// Instances are counted until they are collected. Going over the limit is
+// reported at the call to the class.
+func (i *Interpreter) newInstance(class *LoxClass) *LoxInstance {
+	if i.limits.MaxInstances > 0 && i.instances.Load() >= i.limits.MaxInstances {
+		// Some of the instances may be unreachable but not yet collected
+		collectGarbage()
+		if i.instances.Load() >= i.limits.MaxInstances {
+			i.exceeded(nil, "instance", i.limits.MaxInstances)
+		}
+	}
+
+	instance := NewLoxInstance(class)
+	i.instances.Add(1)
+	runtime.AddCleanup(instance, func(instances *atomic.Int64) {
+		instances.Add(-1)
+	}, &i.instances)
+	return instance
+}
+
+// How long to wait for the cleanups of collected objects to run
+const cleanupTimeout = time.Second
+
+// Runs a full collection and waits until the cleanups it queued have run, so
+// that the count of instances only includes those still reachable. The
+// cleanups are waited for through one of their own, queued for an object
+// that is collected at the same time.
+func collectGarbage() {
+	done := make(chan struct{})
+	dropSentinel(done)
+	runtime.GC()
+	select {
+	case <-done:
+	case <-time.After(cleanupTimeout):
+	}
+	// Cleanups can run concurrently, so let any that are still going finish
+	for range 10 {
+		runtime.Gosched()
+	}
+}
+
+// The sentinel holds a pointer so that it isn't batched with other small
+// objects, which could keep it from being collected.
+//
+//go:noinline
+func dropSentinel(done chan struct{}) {
+	sentinel := &struct{ _ *int }{}
+	runtime.AddCleanup(sentinel, func(done chan struct{}) {
+		close(done)
+	}, done)
+}
@@ -0,0 +1,113 @@
+package interpreter
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func requireLimitError(t *testing.T, err error, message string) *RuntimeError {
+	var limitError *LimitError
+	require.ErrorAs(t, err, &limitError)
+	require.Equal(t, message, limitError.Error())
+	var runtimeError *RuntimeError
+	require.ErrorAs(t, err, &runtimeError)
+	return runtimeError
+}
+
+func TestStepLimit(t *testing.T) {
+	interpreter := NewInterpreter(WithLimits(Limits{MaxSteps: 1000}))
+	_, err := interpreter.Eval(context.Background(), "var i = 0;\nwhile (true) i = i + 1;")
+	runtimeError := requireLimitError(t, err, "Exceeded the step limit of 1000.")
+	require.Equal(t, 2, runtimeError.Span().Start.Line)
+
+	_, err = NewInterpreter(WithLimits(Limits{MaxSteps: 2}), WithStdout(io.Discard)).Eval(context.Background(), "print 1;\nprint 2;")
+	runtimeError = requireLimitError(t, err, "Exceeded the step limit of 2.")
+	require.Equal(t, "2:7-2:8", runtimeError.Span().String())
+
+	// The budget is for each run
+	for range 3 {
+		_, err = interpreter.Eval(context.Background(), "var i = 0; while (i < 50) i = i + 1;")
+		require.NoError(t, err)
+	}
+}
+
+func TestDefaultCallDepthLimitStopsRunawayRecursion(t *testing.T) {
+	interpreter := NewInterpreter()
+	_, err := interpreter.Eval(context.Background(), "fun f(n) { return 1 + f(n + 1); }\nf(0);")
+	runtimeError := requireLimitError(t, err, "Exceeded the call depth limit of 10000.")
+	require.Len(t, runtimeError.StackTrace(), DEFAULT_MAX_CALL_DEPTH)
+	require.Empty(t, interpreter.frames)
+
+	// Tail calls don't use up the depth
+	value, err := interpreter.Eval(context.Background(), `
+fun count(n) { if (n == 0) return "done"; return count(n - 1); }
+count(50000);
+`)
+	require.NoError(t, err)
+	require.Equal(t, "done", value)
+}
+
+func TestCallDepthLimit(t *testing.T) {
+	interpreter := NewInterpreter(WithLimits(Limits{MaxCallDepth: 10}))
+	_, err := interpreter.Eval(context.Background(), "fun f(n) { if (n > 0) return 1 + f(n - 1); return 0; } f(9);")
+	require.NoError(t, err)
+	_, err = interpreter.Eval(context.Background(), "f(10);")
+	runtimeError := requireLimitError(t, err, "Exceeded the call depth limit of 10.")
+	require.Equal(t, 1, runtimeError.Span().Start.Line)
+}
+
+func TestInstanceLimit(t *testing.T) {
+	interpreter := NewInterpreter(WithLimits(Limits{MaxInstances: 3}))
+	require.NoError(t, interpreter.Exec("class Point {} var a = Point(); var b = Point(); var c = Point();"))
+	err := interpreter.Exec("\nvar d = Point();")
+	runtimeError := requireLimitError(t, err, "Exceeded the instance limit of 3.")
+	require.Equal(t, "2:15-2:16", runtimeError.Span().String())
+
+	err = interpreter.Exec("fun make() { return Point(); }\nmake();")
+	runtimeError = requireLimitError(t, err, "Exceeded the instance limit of 3.")
+	require.Equal(t, "1:27-1:28", runtimeError.Span().String())
+}
+
+func TestInstanceLimitCountsOnlyReachableInstances(t *testing.T) {
+	interpreter := NewInterpreter(WithLimits(Limits{MaxInstances: 10}))
+	require.NoError(t, interpreter.Exec(`
+class A {}
+var kept = A();
+for (var n = 0; n < 10000; n = n + 1) { A(); }
+`))
+
+	err := interpreter.Exec("var list = nil; for (var n = 0; n < 20; n = n + 1) { var a = A(); a.next = list; list = a; }")
+	requireLimitError(t, err, "Exceeded the instance limit of 10.")
+}
+
+func TestStringLimit(t *testing.T) {
+	interpreter := NewInterpreter(WithLimits(Limits{MaxStringBytes: 64}))
+	_, err := interpreter.Eval(context.Background(), `var s = "ab"; while (true) s = s + s;`)
+	runtimeError := requireLimitError(t, err, "Exceeded the string byte limit of 64.")
+	require.Equal(t, "+", runtimeError.token.Lexeme)
+
+	interpreter.DefineNative("banner", 0, func(*Interpreter, []Value) (Value, error) {
+		return strings.Repeat("=", 100), nil
+	})
+	_, err = interpreter.Eval(context.Background(), "\nbanner();")
+	runtimeError = requireLimitError(t, err, "Exceeded the string byte limit of 64.")
+	require.Equal(t, 2, runtimeError.Span().Start.Line)
+
+	// Passing strings around doesn't make new ones
+	_, err = interpreter.Eval(context.Background(), `
+var s = "0123456789";
+fun id(s) { return s; }
+for (var n = 0; n < 100; n = n + 1) id(s);
+`)
+	require.NoError(t, err)
+
+	_, err = interpreter.Eval(context.Background(), `
+var s = "0123456789";
+for (var n = 0; n < 100; n = n + 1) s.substring(0, 5);
+`)
+	requireLimitError(t, err, "Exceeded the string byte limit of 64.")
+}
@@ -0,0 +1,51 @@
+package interpreter
+
+import (
+	"fmt"
+	"strings"
+)
+
+var (
+	_ fmt.Stringer = (*LoxList)(nil)
+)
+
+type LoxList struct {
+	elements []any
+}
+
+func NewLoxList(elements []any) *LoxList {
+	return &LoxList{elements: elements}
+}
+
+// A list can end up inside itself, so one that is already being printed is
+// shown as [...].
+func (l *LoxList) String() string {
+	return formatElements(l, map[any]bool{})
+}
+
+// Formats a list or tuple, passing seen on to the lists and tuples inside it.
+func formatElements(container any, seen map[any]bool) string {
+	opening, closing, elements := "[", "]", []any(nil)
+	switch container := container.(type) {
+	case *LoxList:
+		elements = container.elements
+	case *LoxTuple:
+		opening, closing, elements = "(", ")", container.elements
+	}
+	if seen[container] {
+		return opening + "..." + closing
+	}
+	seen[container] = true
+	defer delete(seen, container)
+
+	elementStrings := []string{}
+	for _, element := range elements {
+		switch element.(type) {
+		case *LoxList, *LoxTuple:
+			elementStrings = append(elementStrings, formatElements(element, seen))
+		default:
+			elementStrings = append(elementStrings, stringify(element))
+		}
+	}
+	return opening + strings.Join(elementStrings, ", ") + closing
+}
@@ -0,0 +1,279 @@
+package interpreter
+
+import (
+	"cmp"
+	"math"
+	"math/big"
+	"strconv"
+
+	"github.com/DanielleB-R/golox/interpreter/token"
+)
+
+// Numbers are int64, float64, *big.Int or *Decimal. Arithmetic on two
+// integers stays integral and fails on overflow rather than silently
+// wrapping. Division of two integers is only integral when it is exact, so
+// 7 / 2 is still 3.5.
+//
+// When the operands differ, the exact kinds promote upwards, from int64 to
+// *big.Int to *Decimal, and an int64 mixed with a float64 becomes a float64.
+// A float64 can't be mixed with a *big.Int or a *Decimal, since that would
+// silently throw away the exactness the script asked for; the conversion
+// natives have to be used instead.
+
+type numberKind int
+
+const (
+	NOT_A_NUMBER numberKind = iota
+	INTEGER
+	BIG_INTEGER
+	DECIMAL
+	FLOAT
+)
+
+func kindOf(value any) numberKind {
+	switch value.(type) {
+	case int64:
+		return INTEGER
+	case *big.Int:
+		return BIG_INTEGER
+	case *Decimal:
+		return DECIMAL
+	case float64:
+		return FLOAT
+	}
+	return NOT_A_NUMBER
+}
+
+func isNumber(value any) bool {
+	return kindOf(value) != NOT_A_NUMBER
+}
+
+func toFloat(number any) float64 {
+	switch number := number.(type) {
+	case int64:
+		return float64(number)
+	case float64:
+		return number
+	case *big.Int:
+		f, _ := new(big.Float).SetInt(number).Float64()
+		return f
+	case *Decimal:
+		f, _ := number.Rat().Float64()
+		return f
+	}
+	return math.NaN()
+}
+
+// Converts an exact number up to the given kind.
+func promote(number any, kind numberKind) any {
+	switch kind {
+	case FLOAT:
+		return toFloat(number)
+	case BIG_INTEGER:
+		if i, ok := number.(int64); ok {
+			return big.NewInt(i)
+		}
+	case DECIMAL:
+		switch number := number.(type) {
+		case int64:
+			return NewDecimal(big.NewInt(number), 0)
+		case *big.Int:
+			return decimalFromInt(number)
+		}
+	}
+	return number
+}
+
+func negate(operator *token.Token, operand any) any {
+	switch operand := operand.(type) {
+	case int64:
+		if operand == math.MinInt64 {
+			panic(integerOverflow(operator))
+		}
+		return -operand
+	case *big.Int:
+		return new(big.Int).Neg(operand)
+	case *Decimal:
+		return operand.Neg()
+	}
+	return -operand.(float64)
+}
+
+func arithmetic(operator *token.Token, left any, right any) any {
+	l, r := checkNumberOperands(operator, left, right)
+	switch l := l.(type) {
+	case int64:
+		return integerArithmetic(operator, l, r.(int64))
+	case *big.Int:
+		return bigIntegerArithmetic(operator, l, r.(*big.Int))
+	case *Decimal:
+		return decimalArithmetic(operator, l, r.(*Decimal))
+	}
+
+	leftFloat, rightFloat := l.(float64), r.(float64)
+	switch operator.TokenType {
+	case token.PLUS:
+		return leftFloat + rightFloat
+	case token.MINUS:
+		return leftFloat - rightFloat
+	case token.STAR:
+		return leftFloat * rightFloat
+	case token.SLASH:
+		return leftFloat / rightFloat
+	}
+
+	// Should be unreachable
+	return nil
+}
+
+func integerArithmetic(operator *token.Token, left int64, right int64) any {
+	switch operator.TokenType {
+	case token.PLUS:
+		result := left + right
+		if (result > left) != (right > 0) {
+			panic(integerOverflow(operator))
+		}
+		return result
+	case token.MINUS:
+		result := left - right
+		if (result < left) != (right > 0) {
+			panic(integerOverflow(operator))
+		}
+		return result
+	case token.STAR:
+		if left == 0 || right == 0 {
+			return int64(0)
+		}
+		result := left * right
+		if result/right != left || (left == -1 && right == math.MinInt64) || (right == -1 && left == math.MinInt64) {
+			panic(integerOverflow(operator))
+		}
+		return result
+	case token.SLASH:
+		if right == 0 || left%right != 0 {
+			return float64(left) / float64(right)
+		}
+		if left == math.MinInt64 && right == -1 {
+			panic(integerOverflow(operator))
+		}
+		return left / right
+	}
+
+	// Should be unreachable
+	return nil
+}
+
+// Inexact division of big integers gives a decimal, keeping the result exact
+// as far as it can be.
+func bigIntegerArithmetic(operator *token.Token, left *big.Int, right *big.Int) any {
+	switch operator.TokenType {
+	case token.PLUS:
+		return new(big.Int).Add(left, right)
+	case token.MINUS:
+		return new(big.Int).Sub(left, right)
+	case token.STAR:
+		return new(big.Int).Mul(left, right)
+	case token.SLASH:
+		if right.Sign() == 0 {
+			panic(divisionByZero(operator))
+		}
+		quotient, remainder := new(big.Int).QuoRem(left, right, new(big.Int))
+		if remainder.Sign() == 0 {
+			return quotient
+		}
+		return decimalFromInt(left).Quo(decimalFromInt(right))
+	}
+
+	// Should be unreachable
+	return nil
+}
+
+func decimalArithmetic(operator *token.Token, left *Decimal, right *Decimal) any {
+	switch operator.TokenType {
+	case token.PLUS:
+		return left.Add(right)
+	case token.MINUS:
+		return left.Sub(right)
+	case token.STAR:
+		return left.Mul(right)
+	case token.SLASH:
+		if right.Sign() == 0 {
+			panic(divisionByZero(operator))
+		}
+		return left.Quo(right)
+	}
+
+	// Should be unreachable
+	return nil
+}
+
+// Numbers of different kinds are compared exactly, as numbersEqual compares
+// them, since converting one to the kind of the other can round it.
+func compareNumbers(operator *token.Token, left any, right any) int {
+	l, r := checkNumberOperands(operator, left, right)
+	if kindOf(left) != kindOf(right) {
+		leftRat, ok := toRat(left)
+		rightRat, ok2 := toRat(right)
+		if ok && ok2 {
+			return leftRat.Cmp(rightRat)
+		}
+	}
+	switch l := l.(type) {
+	case int64:
+		return cmp.Compare(l, r.(int64))
+	case *big.Int:
+		return l.Cmp(r.(*big.Int))
+	case *Decimal:
+		return l.Cmp(r.(*Decimal))
+	}
+	return cmp.Compare(l.(float64), r.(float64))
+}
+
+// Numbers of different kinds are equal when they have exactly the same
+// value, so 1 == 1.0 and 1n == 1.00d, but 9007199254740993 != 9007199254740992.0.
+func numbersEqual(left any, right any) bool {
+	l, ok := left.(float64)
+	r, ok2 := right.(float64)
+	if ok && ok2 {
+		return l == r
+	}
+
+	leftRat, ok := toRat(left)
+	rightRat, ok2 := toRat(right)
+	return ok && ok2 && leftRat.Cmp(rightRat) == 0
+}
+
+func toRat(number any) (*big.Rat, bool) {
+	switch number := number.(type) {
+	case int64:
+		return new(big.Rat).SetInt64(number), true
+	case *big.Int:
+		return new(big.Rat).SetInt(number), true
+	case *Decimal:
+		return number.Rat(), true
+	case float64:
+		if math.IsInf(number, 0) || math.IsNaN(number) {
+			return nil, false
+		}
+		return new(big.Rat).SetFloat64(number), true
+	}
+	return nil, false
+}
+
+// Floats are written out in full unless they are very large or very small,
+// so that a float holding an integer prints the same way as the integer.
+func formatFloat(f float64) string {
+	magnitude := math.Abs(f)
+	if f == 0 || (magnitude >= 1e-6 && magnitude < 1e21) {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+func integerOverflow(operator *token.Token) *RuntimeError {
+	return &RuntimeError{code: ARITHMETIC_ERROR, token: operator, message: "Integer overflow."}
+}
+
+func divisionByZero(operator *token.Token) *RuntimeError {
+	return &RuntimeError{code: ARITHMETIC_ERROR, token: operator, message: "Division by zero."}
+}
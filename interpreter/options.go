@@ -0,0 +1,75 @@
+package interpreter
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// An Option configures an Interpreter as it is made.
+type Option func(*Interpreter)
+
+// Sends the output of print statements to w rather than standard output.
+func WithStdout(w io.Writer) Option {
+	return func(i *Interpreter) {
+		i.stdout = w
+	}
+}
+
+// Sends errors and warnings reported by RunFile and RunPrompt to w rather
+// than standard error.
+func WithStderr(w io.Writer) Option {
+	return func(i *Interpreter) {
+		i.stderr = w
+	}
+}
+
+// Reads the input for RunPrompt from r rather than standard input.
+func WithStdin(r io.Reader) Option {
+	return func(i *Interpreter) {
+		i.stdin = bufio.NewReader(r)
+	}
+}
+
+// Standard input is left to readLine, so that interpreters which never read
+// it don't buffer any of it.
+func defaultOptions() []Option {
+	return []Option{WithStdout(os.Stdout), WithStderr(os.Stderr), WithLimits(Limits{})}
+}
+
+// Every interpreter reading standard input shares one buffer, so that none
+// of them reads ahead into input that another one is waiting for.
+var standardInput = sync.OnceValue(func() *bufio.Reader {
+	return bufio.NewReader(os.Stdin)
+})
+
+// Where print statements write to, for natives that want to write there too
+func (i *Interpreter) Stdout() io.Writer {
+	return i.stdout
+}
+
+// The context of the code being run, for natives that call back into Lox
+func (i *Interpreter) Context() context.Context {
+	return i.ctx
+}
+
+// Where errors are reported
+func (i *Interpreter) Stderr() io.Writer {
+	return i.stderr
+}
+
+// Reads a line of input without its line ending. It returns false at the end
+// of the input, or if the input can't be read.
+func (i *Interpreter) readLine() (string, bool) {
+	if i.stdin == nil {
+		i.stdin = standardInput()
+	}
+	line, err := i.stdin.ReadString('\n')
+	if err != nil && line == "" {
+		return "", false
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r"), true
+}
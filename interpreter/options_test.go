@@ -0,0 +1,32 @@
+package interpreter
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrintWritesToStdout(t *testing.T) {
+	var stdout bytes.Buffer
+	interpreter := NewInterpreter(WithStdout(&stdout))
+	require.NoError(t, interpreter.Exec(`print "hello"; print 1.5; print nil;`))
+	require.Equal(t, "hello\n1.5\nnil\n", stdout.String())
+	require.Same(t, &stdout, interpreter.Stdout())
+}
+
+func TestStandardInputIsShared(t *testing.T) {
+	require.Nil(t, NewInterpreter().stdin)
+	require.Same(t, standardInput(), standardInput())
+}
+
+func TestRunPromptUsesInterpreterStreams(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	stdin := strings.NewReader("var name = \"Lox\";\nprint \"hi \" + name;\nprint nil + 1;\n")
+
+	RunPrompt(DefaultRunOptions(), WithStdin(stdin), WithStdout(&stdout), WithStderr(&stderr))
+	require.Equal(t, "> > hi Lox\n> > ", stdout.String())
+	require.Contains(t, stderr.String(), "error[E0403]")
+	require.NotContains(t, stderr.String(), colourReset)
+}
@@ -9,6 +9,9 @@ type Parser struct {
 	tokens  []*token.Token
 	current int
 	errors  ParseErrors
+	// How many blocks and class bodies we're inside, so that synchronizing
+	// doesn't run past the end of them
+	depth int
 }
 
 func NewParser(tokens []*token.Token) *Parser {
@@ -20,22 +23,28 @@ func NewParser(tokens []*token.Token) *Parser {
 }
 
 func (p *Parser) Parse() ([]ast.Stmt, error) {
+	statements, err := p.ParsePartial()
+	if err != nil {
+		return nil, err
+	}
+	return statements, nil
+}
+
+// Parses as much of the program as it can, returning the statements along
+// with any errors. Code that couldn't be parsed is replaced with BadStmt and
+// BadExpr nodes, so the result is only fit for tools to look at.
+func (p *Parser) ParsePartial() ([]ast.Stmt, error) {
 	statements := []ast.Stmt{}
 	for !p.isAtEnd() {
-		stmt, err := p.declaration()
+		stmt, err := p.recoveringDeclaration()
 		if err != nil {
-			parseErr, ok := err.(*ParseError)
-			if !ok {
-				return statements, err
-			}
-			p.errors = append(p.errors, parseErr)
-			p.synchronize()
+			return statements, err
 		}
 		statements = append(statements, stmt)
 	}
 
 	if len(p.errors) != 0 {
-		return nil, p.errors
+		return statements, p.errors
 	}
 
 	return statements, nil
@@ -43,6 +52,29 @@ func (p *Parser) Parse() ([]ast.Stmt, error) {
 
 // Grammar rules
 
+// Parses a declaration, and if that fails records the error and skips ahead
+// to somewhere sensible to carry on from, returning a BadStmt for the tokens
+// that were skipped.
+func (p *Parser) recoveringDeclaration() (ast.Stmt, error) {
+	start := p.current
+	stmt, err := p.declaration()
+	if err == nil {
+		return stmt, nil
+	}
+
+	parseErr, ok := err.(*ParseError)
+	if !ok {
+		return nil, err
+	}
+	p.errors = append(p.errors, parseErr)
+	p.synchronize()
+
+	return &ast.BadStmt{
+		From: p.tokens[start],
+		To:   p.tokens[max(p.current-1, start)],
+	}, nil
+}
+
 func (p *Parser) declaration() (ast.Stmt, error) {
 	if p.match(token.CLASS) {
 		return p.class()
@@ -77,14 +109,22 @@ func (p *Parser) class() (ast.Stmt, error) {
 	}
 
 	methods := []*ast.Function{}
+	p.depth++
 	for !p.check(token.RIGHT_BRACE) && !p.isAtEnd() {
 		fn, err := p.function("method")
 		if err != nil {
-			return nil, err
+			parseErr, ok := err.(*ParseError)
+			if !ok {
+				return nil, err
+			}
+			p.errors = append(p.errors, parseErr)
+			p.synchronize()
+			continue
 		}
 
 		methods = append(methods, fn.(*ast.Function))
 	}
+	p.depth--
 
 	_, err = p.consume(token.RIGHT_BRACE, "Expect '}' after class body.")
 	if err != nil {
@@ -112,7 +152,7 @@ func (p *Parser) function(kind string) (ast.Stmt, error) {
 	if !p.check(token.RIGHT_PAREN) {
 		for {
 			if len(parameters) >= 255 {
-				return nil, &ParseError{token: p.peek(), message: "Can't have more than 255 parameters."}
+				return nil, &ParseError{token: p.peek(), code: TOO_MANY_ARGUMENTS_ERROR, message: "Can't have more than 255 parameters."}
 			}
 			name, err := p.consume(token.IDENTIFIER, "Expect parameter name.")
 			if err != nil {
@@ -148,6 +188,10 @@ func (p *Parser) function(kind string) (ast.Stmt, error) {
 }
 
 func (p *Parser) varDeclaration() (ast.Stmt, error) {
+	if p.check(token.LEFT_PAREN) || p.check(token.LEFT_BRACKET) {
+		return p.destructuringDeclaration()
+	}
+
 	name, err := p.consume(token.IDENTIFIER, "Expect variable name.")
 	if err != nil {
 		return nil, err
@@ -171,13 +215,50 @@ func (p *Parser) varDeclaration() (ast.Stmt, error) {
 	}, nil
 }
 
+func (p *Parser) destructuringDeclaration() (ast.Stmt, error) {
+	pattern, err := p.pattern()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = p.consume(token.EQUAL, "Expect '=' after destructuring pattern.")
+	if err != nil {
+		return nil, err
+	}
+	initializer, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = p.consume(token.SEMICOLON, "Expect ';' after variable declaration.")
+	if err != nil {
+		return nil, err
+	}
+	return &ast.VarDestructure{
+		Pattern:     pattern,
+		Initializer: initializer,
+	}, nil
+}
+
 func (p *Parser) statement() (ast.Stmt, error) {
+	if p.check(token.IDENTIFIER) && p.checkNext(token.COLON) {
+		return p.labeledStatement()
+	}
+	if p.match(token.BREAK) {
+		return p.breakStatement()
+	}
+	if p.match(token.CONTINUE) {
+		return p.continueStatement()
+	}
 	if p.match(token.FOR) {
-		return p.forStatement()
+		return p.forStatement(nil)
 	}
 	if p.match(token.IF) {
 		return p.ifStatement()
 	}
+	if p.match(token.MATCH) {
+		return p.matchStatement()
+	}
 	if p.match(token.PRINT) {
 		return p.printStatement()
 	}
@@ -194,12 +275,62 @@ func (p *Parser) statement() (ast.Stmt, error) {
 		}, nil
 	}
 	if p.match(token.WHILE) {
-		return p.whileStatement()
+		return p.whileStatement(nil)
 	}
 	return p.expressionStatement()
 }
 
-func (p *Parser) forStatement() (ast.Stmt, error) {
+func (p *Parser) labeledStatement() (ast.Stmt, error) {
+	label := p.advance()
+	p.advance()
+
+	if p.match(token.FOR) {
+		return p.forStatement(label)
+	}
+	if p.match(token.WHILE) {
+		return p.whileStatement(label)
+	}
+	return nil, &ParseError{token: p.peek(), code: EXPECTED_TOKEN_ERROR, message: "Expect loop after label."}
+}
+
+func (p *Parser) breakStatement() (ast.Stmt, error) {
+	keyword := p.previous()
+
+	var label *token.Token
+	if p.match(token.IDENTIFIER) {
+		label = p.previous()
+	}
+	_, err := p.consume(token.SEMICOLON, "Expect ';' after 'break'.")
+	if err != nil {
+		return nil, err
+	}
+
+	return &ast.Break{
+		Keyword: keyword,
+		Label:   label,
+	}, nil
+}
+
+func (p *Parser) continueStatement() (ast.Stmt, error) {
+	keyword := p.previous()
+
+	var label *token.Token
+	if p.match(token.IDENTIFIER) {
+		label = p.previous()
+	}
+	_, err := p.consume(token.SEMICOLON, "Expect ';' after 'continue'.")
+	if err != nil {
+		return nil, err
+	}
+
+	return &ast.Continue{
+		Keyword: keyword,
+		Label:   label,
+	}, nil
+}
+
+func (p *Parser) forStatement(label *token.Token) (ast.Stmt, error) {
+	keyword := p.previous()
 	_, err := p.consume(token.LEFT_PAREN, "Expect '(' after 'for'.")
 	if err != nil {
 		return nil, err
@@ -249,23 +380,15 @@ func (p *Parser) forStatement() (ast.Stmt, error) {
 		return nil, err
 	}
 
-	if increment != nil {
-		body = &ast.Block{
-			Statements: []ast.Stmt{
-				body,
-				&ast.ExpressionStmt{
-					Expression: increment,
-				},
-			},
-		}
-	}
-
 	if condition == nil {
 		condition = &ast.Literal{Value: true}
 	}
 	body = &ast.While{
+		Keyword:   keyword,
+		Label:     label,
 		Condition: condition,
 		Body:      body,
+		Increment: increment,
 	}
 
 	if initializer != nil {
@@ -310,6 +433,209 @@ func (p *Parser) ifStatement() (ast.Stmt, error) {
 	}, nil
 }
 
+func (p *Parser) matchStatement() (ast.Stmt, error) {
+	keyword := p.previous()
+	_, err := p.consume(token.LEFT_PAREN, "Expect '(' after 'match'.")
+	if err != nil {
+		return nil, err
+	}
+	subject, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	_, err = p.consume(token.RIGHT_PAREN, "Expect ')' after match value.")
+	if err != nil {
+		return nil, err
+	}
+	_, err = p.consume(token.LEFT_BRACE, "Expect '{' before match cases.")
+	if err != nil {
+		return nil, err
+	}
+
+	cases := []*ast.MatchCase{}
+	for !p.check(token.RIGHT_BRACE) && !p.isAtEnd() {
+		matchCase, err := p.matchCase()
+		if err != nil {
+			return nil, err
+		}
+		cases = append(cases, matchCase)
+	}
+
+	_, err = p.consume(token.RIGHT_BRACE, "Expect '}' after match cases.")
+	if err != nil {
+		return nil, err
+	}
+
+	return &ast.Match{
+		Keyword: keyword,
+		Subject: subject,
+		Cases:   cases,
+	}, nil
+}
+
+func (p *Parser) matchCase() (*ast.MatchCase, error) {
+	keyword, err := p.consume(token.CASE, "Expect 'case'.")
+	if err != nil {
+		return nil, err
+	}
+
+	patterns := []ast.Pattern{}
+	for {
+		pattern, err := p.pattern()
+		if err != nil {
+			return nil, err
+		}
+		patterns = append(patterns, pattern)
+		if !p.match(token.COMMA) {
+			break
+		}
+	}
+
+	var guard ast.Expr
+	if p.match(token.IF) {
+		guard, err = p.expression()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	_, err = p.consume(token.ARROW, "Expect '=>' after case pattern.")
+	if err != nil {
+		return nil, err
+	}
+	body, err := p.statement()
+	if err != nil {
+		return nil, err
+	}
+
+	return &ast.MatchCase{
+		Keyword:  keyword,
+		Patterns: patterns,
+		Guard:    guard,
+		Body:     body,
+	}, nil
+}
+
+func (p *Parser) pattern() (ast.Pattern, error) {
+	if p.match(token.FALSE) {
+		return &ast.LiteralPattern{Token: p.previous(), Value: false}, nil
+	}
+	if p.match(token.TRUE) {
+		return &ast.LiteralPattern{Token: p.previous(), Value: true}, nil
+	}
+	if p.match(token.NIL) {
+		return &ast.LiteralPattern{Token: p.previous(), Value: nil}, nil
+	}
+	if p.match(token.NUMBER, token.STRING) {
+		return &ast.LiteralPattern{Token: p.previous(), Value: p.previous().Literal}, nil
+	}
+	if p.match(token.MINUS) {
+		number, err := p.consume(token.NUMBER, "Expect number after '-' in pattern.")
+		if err != nil {
+			return nil, err
+		}
+		return &ast.LiteralPattern{Token: number, Value: negate(number, number.Literal)}, nil
+	}
+
+	if p.match(token.LEFT_BRACKET) {
+		bracket := p.previous()
+		elements, err := p.patternElements(token.RIGHT_BRACKET, "Expect ']' after list pattern.")
+		if err != nil {
+			return nil, err
+		}
+		return &ast.ListPattern{Bracket: bracket, Elements: elements}, nil
+	}
+	if p.match(token.LEFT_PAREN) {
+		paren := p.previous()
+		elements, err := p.patternElements(token.RIGHT_PAREN, "Expect ')' after tuple pattern.")
+		if err != nil {
+			return nil, err
+		}
+		return &ast.TuplePattern{Paren: paren, Elements: elements}, nil
+	}
+
+	name, err := p.consume(token.IDENTIFIER, "Expect pattern.")
+	if err != nil {
+		return nil, err
+	}
+	if name.Lexeme == "_" {
+		return &ast.WildcardPattern{Token: name}, nil
+	}
+	if !p.match(token.LEFT_BRACE) {
+		return &ast.BindingPattern{Name: name}, nil
+	}
+
+	fields := []*ast.FieldPattern{}
+	if !p.check(token.RIGHT_BRACE) {
+		for {
+			field, err := p.consume(token.IDENTIFIER, "Expect field name.")
+			if err != nil {
+				return nil, err
+			}
+			// A field with no pattern of its own binds a variable of the same name.
+			var pattern ast.Pattern = &ast.BindingPattern{Name: field}
+			if p.match(token.COLON) {
+				pattern, err = p.pattern()
+				if err != nil {
+					return nil, err
+				}
+			}
+			fields = append(fields, &ast.FieldPattern{Name: field, Pattern: pattern})
+			if !p.match(token.COMMA) {
+				break
+			}
+		}
+	}
+	_, err = p.consume(token.RIGHT_BRACE, "Expect '}' after class pattern fields.")
+	if err != nil {
+		return nil, err
+	}
+
+	return &ast.ClassPattern{
+		Class:  &ast.Variable{Name: name},
+		Fields: fields,
+	}, nil
+}
+
+func (p *Parser) patternElements(closing int, message string) ([]ast.Pattern, error) {
+	elements := []ast.Pattern{}
+	if !p.check(closing) {
+		for {
+			if p.match(token.ELLIPSIS) {
+				ellipsis := p.previous()
+				name, err := p.consume(token.IDENTIFIER, "Expect name after '...'.")
+				if err != nil {
+					return nil, err
+				}
+				var binding ast.Pattern = &ast.BindingPattern{Name: name}
+				if name.Lexeme == "_" {
+					binding = &ast.WildcardPattern{Token: name}
+				}
+				elements = append(elements, &ast.RestPattern{Ellipsis: ellipsis, Binding: binding})
+				if !p.check(closing) {
+					return nil, &ParseError{token: p.peek(), code: PATTERN_SYNTAX_ERROR, message: "Rest pattern must be last."}
+				}
+				break
+			}
+
+			element, err := p.pattern()
+			if err != nil {
+				return nil, err
+			}
+			elements = append(elements, element)
+			if !p.match(token.COMMA) {
+				break
+			}
+		}
+	}
+
+	_, err := p.consume(closing, message)
+	if err != nil {
+		return nil, err
+	}
+	return elements, nil
+}
+
 func (p *Parser) printStatement() (ast.Stmt, error) {
 	expr, err := p.expression()
 	if err != nil {
@@ -336,6 +662,18 @@ func (p *Parser) returnStatement() (ast.Stmt, error) {
 			return nil, err
 		}
 	}
+	// Several comma-separated values are returned together as a tuple.
+	if value != nil && p.check(token.COMMA) {
+		elements := []ast.Expr{value}
+		for p.match(token.COMMA) {
+			element, err := p.expression()
+			if err != nil {
+				return nil, err
+			}
+			elements = append(elements, element)
+		}
+		value = &ast.Tuple{Elements: elements}
+	}
 	_, err = p.consume(token.SEMICOLON, "Expect ';' after return value.")
 	if err != nil {
 		return nil, err
@@ -347,7 +685,8 @@ func (p *Parser) returnStatement() (ast.Stmt, error) {
 	}, nil
 }
 
-func (p *Parser) whileStatement() (ast.Stmt, error) {
+func (p *Parser) whileStatement(label *token.Token) (ast.Stmt, error) {
+	keyword := p.previous()
 	_, err := p.consume(token.LEFT_PAREN, "Expect '(' after 'while'.")
 	if err != nil {
 		return nil, err
@@ -367,6 +706,8 @@ func (p *Parser) whileStatement() (ast.Stmt, error) {
 	}
 
 	return &ast.While{
+		Keyword:   keyword,
+		Label:     label,
 		Condition: condition,
 		Body:      body,
 	}, nil
@@ -375,13 +716,15 @@ func (p *Parser) whileStatement() (ast.Stmt, error) {
 func (p *Parser) block() ([]ast.Stmt, error) {
 	statements := []ast.Stmt{}
 
+	p.depth++
 	for !p.check(token.RIGHT_BRACE) && !p.isAtEnd() {
-		statement, err := p.declaration()
+		statement, err := p.recoveringDeclaration()
 		if err != nil {
 			return nil, err
 		}
 		statements = append(statements, statement)
 	}
+	p.depth--
 
 	_, err := p.consume(token.RIGHT_BRACE, "Expect '}' after block.")
 	if err != nil {
@@ -410,6 +753,7 @@ func (p *Parser) expression() (ast.Expr, error) {
 }
 
 func (p *Parser) assignment() (ast.Expr, error) {
+	start := p.peek()
 	expr, err := p.or()
 	if err != nil {
 		return nil, err
@@ -433,11 +777,13 @@ func (p *Parser) assignment() (ast.Expr, error) {
 				Value:  value,
 			}, nil
 		}
-		// TODO: This should not trigger a resynchronization of the parser
-		return nil, &ParseError{
+		// The parser isn't confused, so there's no need to synchronize
+		p.errors = append(p.errors, &ParseError{
 			token:   equals,
+			code:    ASSIGNMENT_TARGET_ERROR,
 			message: "Invalid assignment target",
-		}
+		})
+		return &ast.BadExpr{From: start, To: p.previous()}, nil
 	}
 
 	return expr, nil
@@ -629,6 +975,7 @@ func (p *Parser) finishCall(callee ast.Expr) (ast.Expr, error) {
 				// NOTE: this should be non-resynchronizing
 				return nil, &ParseError{
 					token:   p.peek(),
+					code:    TOO_MANY_ARGUMENTS_ERROR,
 					message: "Can't have more than 255 arguments.",
 				}
 			}
@@ -658,22 +1005,26 @@ func (p *Parser) finishCall(callee ast.Expr) (ast.Expr, error) {
 func (p *Parser) primary() (ast.Expr, error) {
 	if p.match(token.FALSE) {
 		return &ast.Literal{
+			Token: p.previous(),
 			Value: false,
 		}, nil
 	}
 	if p.match(token.TRUE) {
 		return &ast.Literal{
+			Token: p.previous(),
 			Value: true,
 		}, nil
 	}
 	if p.match(token.NIL) {
 		return &ast.Literal{
+			Token: p.previous(),
 			Value: nil,
 		}, nil
 	}
 
 	if p.match(token.NUMBER, token.STRING) {
 		return &ast.Literal{
+			Token: p.previous(),
 			Value: p.previous().Literal,
 		}, nil
 	}
@@ -707,22 +1058,26 @@ func (p *Parser) primary() (ast.Expr, error) {
 	}
 
 	if p.match(token.LEFT_PAREN) {
+		leftParen := p.previous()
 		expr, err := p.expression()
 		if err != nil {
 			return nil, err
 		}
 
-		_, err = p.consume(token.RIGHT_PAREN, "Expect ')' after expression.")
+		rightParen, err := p.consume(token.RIGHT_PAREN, "Expect ')' after expression.")
 		if err != nil {
 			return nil, err
 		}
 		return &ast.Grouping{
+			LeftParen:  leftParen,
 			Expression: expr,
+			RightParen: rightParen,
 		}, nil
 	}
 
 	return nil, &ParseError{
 		token:   p.peek(),
+		code:    EXPECTED_EXPRESSION_ERROR,
 		message: "Expect expression.",
 	}
 }
@@ -737,6 +1092,14 @@ func (p *Parser) previous() *token.Token {
 	return p.tokens[p.current-1]
 }
 
+func (p *Parser) checkNext(tokenType int) bool {
+	if p.isAtEnd() {
+		return false
+	}
+
+	return p.tokens[p.current+1].TokenType == tokenType
+}
+
 func (p *Parser) isAtEnd() bool {
 	return p.peek().TokenType == token.EOF
 }
@@ -771,10 +1134,14 @@ func (p *Parser) consume(tokenType int, message string) (*token.Token, error) {
 		return p.advance(), nil
 	}
 
-	return nil, &ParseError{token: p.peek(), message: message}
+	return nil, &ParseError{token: p.peek(), code: EXPECTED_TOKEN_ERROR, message: message}
 }
 
 func (p *Parser) synchronize() {
+	// Inside a block the closing brace is left for the block to consume
+	if p.depth > 0 && p.check(token.RIGHT_BRACE) {
+		return
+	}
 	p.advance()
 
 	for !p.isAtEnd() {
@@ -783,7 +1150,11 @@ func (p *Parser) synchronize() {
 		}
 
 		switch p.peek().TokenType {
-		case token.CLASS, token.FUN, token.VAR, token.FOR, token.IF, token.WHILE, token.PRINT, token.RETURN:
+		case token.RIGHT_BRACE:
+			if p.depth > 0 {
+				return
+			}
+		case token.CLASS, token.FUN, token.VAR, token.FOR, token.IF, token.WHILE, token.PRINT, token.RETURN, token.BREAK, token.CONTINUE, token.MATCH:
 			return
 		}
 
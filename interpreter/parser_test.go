@@ -0,0 +1,100 @@
+package interpreter
+
+import (
+	"testing"
+
+	"github.com/DanielleB-R/golox/interpreter/ast"
+	"github.com/stretchr/testify/require"
+)
+
+func parseExpression(t *testing.T, source string) ast.Expr {
+	tokens, err := scan(source + ";")
+	require.NoError(t, err)
+	statements, err := NewParser(tokens).Parse()
+	require.NoError(t, err)
+	return statements[0].(*ast.ExpressionStmt).Expression
+}
+
+func TestExpressionSpans(t *testing.T) {
+	cases := map[string]string{
+		"1 + (2 * 3)":      "1:1-1:12",
+		"-x":               "1:1-1:3",
+		"a.b.c = \"long\"": "1:1-1:15",
+		"f(1, g())":        "1:1-1:10",
+		"super.method":     "1:1-1:13",
+		"x or nil":         "1:1-1:9",
+	}
+
+	for source, span := range cases {
+		t.Run(source, func(t *testing.T) {
+			require.Equal(t, span, ast.SpanOf(parseExpression(t, source)).String())
+		})
+	}
+}
+
+func parsePartial(t *testing.T, source string) ([]ast.Stmt, ParseErrors) {
+	tokens, err := scan(source)
+	require.NoError(t, err)
+	statements, err := NewParser(tokens).ParsePartial()
+	var errors ParseErrors
+	require.ErrorAs(t, err, &errors)
+	return statements, errors
+}
+
+func TestParsePartialKeepsGoodStatements(t *testing.T) {
+	statements, errors := parsePartial(t, "print 1;\nvar = 2;\nprint 3;")
+	require.Len(t, errors, 1)
+	require.Len(t, statements, 3)
+
+	bad := statements[1].(*ast.BadStmt)
+	require.Equal(t, "var", bad.From.Lexeme)
+	require.Equal(t, ";", bad.To.Lexeme)
+	require.IsType(t, &ast.Print{}, statements[2])
+}
+
+func TestParsePartialRecoversInsideBlocks(t *testing.T) {
+	statements, errors := parsePartial(t, "fun f() {\n  var = 1;\n  print 2;\n}\nprint 3;")
+	require.Len(t, errors, 1)
+	require.Equal(t, 2, errors[0].Span().Start.Line)
+	require.Len(t, statements, 2)
+
+	body := statements[0].(*ast.Function).Body
+	require.Len(t, body, 2)
+	require.IsType(t, &ast.BadStmt{}, body[0])
+	require.IsType(t, &ast.Print{}, body[1])
+}
+
+func TestParsePartialLeavesClosingBraceToBlock(t *testing.T) {
+	statements, errors := parsePartial(t, "{ print 1 }\nprint 2;")
+	require.Len(t, errors, 1)
+	require.Len(t, statements, 2)
+	require.IsType(t, &ast.BadStmt{}, statements[0].(*ast.Block).Statements[0])
+}
+
+func TestParsePartialRecoversInsideClassBodies(t *testing.T) {
+	statements, errors := parsePartial(t, "class A {\n  one() { return 1 }\n  two() { return 2; }\n}")
+	require.Len(t, errors, 1)
+	require.Len(t, statements, 1)
+
+	methods := statements[0].(*ast.Class).Methods
+	require.Len(t, methods, 2)
+	require.Equal(t, "two", methods[1].Name.Lexeme)
+}
+
+func TestInvalidAssignmentTargetIsBadExpr(t *testing.T) {
+	statements, errors := parsePartial(t, "a + b = c;\nprint 1;")
+	require.Len(t, errors, 1)
+	require.Equal(t, "Invalid assignment target", errors[0].Message())
+	require.Len(t, statements, 2)
+
+	bad := statements[0].(*ast.ExpressionStmt).Expression.(*ast.BadExpr)
+	require.Equal(t, "1:1-1:10", ast.SpanOf(bad).String())
+}
+
+func TestParseReturnsNoStatementsOnError(t *testing.T) {
+	tokens, err := scan("print 1;\nprint;")
+	require.NoError(t, err)
+	statements, err := NewParser(tokens).Parse()
+	require.Error(t, err)
+	require.Nil(t, statements)
+}
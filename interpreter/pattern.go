@@ -0,0 +1,142 @@
+package interpreter
+
+import (
+	"fmt"
+
+	"github.com/DanielleB-R/golox/interpreter/ast"
+	"github.com/DanielleB-R/golox/interpreter/token"
+)
+
+// Matches value against the pattern, defining any bound variables in the
+// current environment. The returned error explains why the value didn't
+// match; errors that are not about the value itself are panicked as usual.
+func (i *Interpreter) matchPattern(pattern ast.Pattern, value any) error {
+	switch pattern := pattern.(type) {
+	case *ast.WildcardPattern:
+		return nil
+	case *ast.BindingPattern:
+		i.environment.Define(pattern.Name.Lexeme, value)
+		return nil
+	case *ast.LiteralPattern:
+		if !isEqual(pattern.Value, value) {
+			return &RuntimeError{
+				token:   pattern.Token,
+				code:    MATCH_ERROR,
+				message: fmt.Sprintf("Expected %s but got %s.", stringify(pattern.Value), stringify(value)),
+			}
+		}
+		return nil
+	case *ast.ListPattern:
+		list, ok := value.(*LoxList)
+		if !ok {
+			return &RuntimeError{
+				token:   pattern.Bracket,
+				code:    MATCH_ERROR,
+				message: fmt.Sprintf("Expected a list but got %s.", stringify(value)),
+			}
+		}
+		return i.matchElements(pattern.Bracket, pattern.Elements, list.elements, func(rest []any) any {
+			return NewLoxList(rest)
+		})
+	case *ast.TuplePattern:
+		tuple, ok := value.(*LoxTuple)
+		if !ok {
+			return &RuntimeError{
+				token:   pattern.Paren,
+				code:    MATCH_ERROR,
+				message: fmt.Sprintf("Expected a tuple but got %s.", stringify(value)),
+			}
+		}
+		return i.matchElements(pattern.Paren, pattern.Elements, tuple.elements, func(rest []any) any {
+			return NewLoxTuple(rest)
+		})
+	case *ast.ClassPattern:
+		class, ok := i.evaluate(pattern.Class).(*LoxClass)
+		if !ok {
+			panic(&RuntimeError{
+				token:   pattern.Class.Name,
+				code:    TYPE_ERROR,
+				message: fmt.Sprintf("'%s' in pattern is not a class.", pattern.Class.Name.Lexeme),
+			})
+		}
+		instance, ok := value.(*LoxInstance)
+		if !ok || !instance.class.IsSubclassOf(class) {
+			return &RuntimeError{
+				token:   pattern.Class.Name,
+				code:    MATCH_ERROR,
+				message: fmt.Sprintf("Expected %s instance but got %s.", class.name, stringify(value)),
+			}
+		}
+		for _, field := range pattern.Fields {
+			fieldValue, ok := instance.fields[field.Name.Lexeme]
+			if !ok {
+				return &RuntimeError{
+					token:   field.Name,
+					code:    MATCH_ERROR,
+					message: fmt.Sprintf("%s has no field '%s'.", instance, field.Name.Lexeme),
+				}
+			}
+			if err := i.matchPattern(field.Pattern, fieldValue); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	// Should be unreachable
+	return nil
+}
+
+// Matches a list or tuple element by element. A trailing rest pattern is
+// matched against the remaining elements, collected by makeRest.
+func (i *Interpreter) matchElements(where *token.Token, patterns []ast.Pattern, values []any, makeRest func([]any) any) error {
+	var rest *ast.RestPattern
+	if len(patterns) > 0 {
+		rest, _ = patterns[len(patterns)-1].(*ast.RestPattern)
+	}
+
+	if rest == nil && len(values) != len(patterns) {
+		return &RuntimeError{
+			token:   where,
+			code:    MATCH_ERROR,
+			message: fmt.Sprintf("Expected %d elements but got %d.", len(patterns), len(values)),
+		}
+	}
+	if rest != nil && len(values) < len(patterns)-1 {
+		return &RuntimeError{
+			token:   where,
+			code:    MATCH_ERROR,
+			message: fmt.Sprintf("Expected at least %d elements but got %d.", len(patterns)-1, len(values)),
+		}
+	}
+
+	for index, pattern := range patterns {
+		if pattern == rest {
+			remaining := make([]any, len(values)-index)
+			copy(remaining, values[index:])
+			return i.matchPattern(rest.Binding, makeRest(remaining))
+		}
+		if err := i.matchPattern(pattern, values[index]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Patterns and guard are evaluated inside the case's own environment, which
+// the body then runs in if the case matches.
+func (i *Interpreter) matchCase(matchCase *ast.MatchCase, subject any, environment *Environment) bool {
+	previous := i.environment
+	defer func() {
+		i.environment = previous
+	}()
+	i.environment = environment
+
+	for _, pattern := range matchCase.Patterns {
+		if i.matchPattern(pattern, subject) != nil {
+			continue
+		}
+		return matchCase.Guard == nil || isTruthy(i.evaluate(matchCase.Guard))
+	}
+	return false
+}
@@ -0,0 +1,202 @@
+package interpreter
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/DanielleB-R/golox/interpreter/token"
+)
+
+// Built-in methods on values that are not instances. They are looked up
+// through ast.Get just like instance methods, and come back bound to their
+// receiver as a NativeFunction.
+type primitiveMethod[T any] struct {
+	arity     int
+	behaviour func(name *token.Token, receiver T, arguments []any) any
+}
+
+func bindPrimitive[T any](methods map[string]primitiveMethod[T], receiver T, name *token.Token, kind string) (*NativeFunction, error) {
+	method, ok := methods[name.Lexeme]
+	if !ok {
+		return nil, &RuntimeError{
+			token:   name,
+			code:    UNDEFINED_PROPERTY_ERROR,
+			message: fmt.Sprintf("Undefined %s method '%s'.", kind, name.Lexeme) + suggestion(name.Lexeme, slices.Collect(maps.Keys(methods))),
+		}
+	}
+
+	return &NativeFunction{
+		name:  kind + "." + name.Lexeme,
+		arity: method.arity,
+		behaviour: func(interpreter *Interpreter, arguments []any) any {
+			return method.behaviour(name, receiver, arguments)
+		},
+	}, nil
+}
+
+// Lengths and positions in strings count code points rather than bytes.
+var stringMethods = map[string]primitiveMethod[string]{
+	"length": {0, func(name *token.Token, s string, arguments []any) any {
+		return int64(utf8.RuneCountInString(s))
+	}},
+	"substring": {2, func(name *token.Token, s string, arguments []any) any {
+		start := integerArgument(name, arguments, 0)
+		end := integerArgument(name, arguments, 1)
+		runes := []rune(s)
+		if start < 0 || end > len(runes) || start > end {
+			panic(&RuntimeError{
+				token:   name,
+				code:    RANGE_ERROR,
+				message: fmt.Sprintf("Substring range [%d, %d) out of bounds for length %d.", start, end, len(runes)),
+			})
+		}
+		return string(runes[start:end])
+	}},
+	"indexOf": {1, func(name *token.Token, s string, arguments []any) any {
+		index := strings.Index(s, stringArgument(name, arguments, 0))
+		if index < 0 {
+			return int64(-1)
+		}
+		return int64(utf8.RuneCountInString(s[:index]))
+	}},
+	"split": {1, func(name *token.Token, s string, arguments []any) any {
+		elements := []any{}
+		for _, part := range strings.Split(s, stringArgument(name, arguments, 0)) {
+			elements = append(elements, part)
+		}
+		return NewLoxList(elements)
+	}},
+	"trim": {0, func(name *token.Token, s string, arguments []any) any {
+		return strings.TrimSpace(s)
+	}},
+	"upper": {0, func(name *token.Token, s string, arguments []any) any {
+		return strings.ToUpper(s)
+	}},
+	"lower": {0, func(name *token.Token, s string, arguments []any) any {
+		return strings.ToLower(s)
+	}},
+	"replace": {2, func(name *token.Token, s string, arguments []any) any {
+		return strings.ReplaceAll(s, stringArgument(name, arguments, 0), stringArgument(name, arguments, 1))
+	}},
+	"startsWith": {1, func(name *token.Token, s string, arguments []any) any {
+		return strings.HasPrefix(s, stringArgument(name, arguments, 0))
+	}},
+	"chars": {0, func(name *token.Token, s string, arguments []any) any {
+		elements := []any{}
+		for _, r := range s {
+			elements = append(elements, string(r))
+		}
+		return NewLoxList(elements)
+	}},
+}
+
+// The receiver can be any kind of number. Rounding gives an integer whenever
+// the result fits in one, and a big integer for big integers and decimals.
+var numberMethods = map[string]primitiveMethod[any]{
+	"floor": {0, func(name *token.Token, n any, arguments []any) any {
+		if d, ok := n.(*Decimal); ok {
+			return d.Floor()
+		}
+		return roundedNumber(n, math.Floor)
+	}},
+	"round": {0, func(name *token.Token, n any, arguments []any) any {
+		if d, ok := n.(*Decimal); ok {
+			return d.Round(0).unscaled
+		}
+		return roundedNumber(n, math.Round)
+	}},
+	"toFixed": {1, func(name *token.Token, n any, arguments []any) any {
+		digits := integerArgument(name, arguments, 0)
+		if digits < 0 {
+			panic(&RuntimeError{code: RANGE_ERROR, token: name, message: "Number of digits must not be negative."})
+		}
+		if f, ok := n.(float64); ok {
+			return strconv.FormatFloat(f, 'f', digits, 64)
+		}
+		return promote(n, DECIMAL).(*Decimal).Round(digits).String()
+	}},
+	"isInteger": {0, func(name *token.Token, n any, arguments []any) any {
+		switch n := n.(type) {
+		case float64:
+			return !math.IsInf(n, 0) && n == math.Trunc(n)
+		case *Decimal:
+			return n.IsInteger()
+		}
+		return true
+	}},
+}
+
+func roundedNumber(n any, round func(float64) float64) any {
+	f, ok := n.(float64)
+	if !ok {
+		return n
+	}
+	rounded := round(f)
+	if rounded >= math.MinInt64 && rounded < math.MaxInt64 {
+		return int64(rounded)
+	}
+	return rounded
+}
+
+var listMethods = map[string]primitiveMethod[*LoxList]{
+	"length": {0, func(name *token.Token, l *LoxList, arguments []any) any {
+		return int64(len(l.elements))
+	}},
+	"get": {1, func(name *token.Token, l *LoxList, arguments []any) any {
+		return l.elements[indexArgument(name, arguments, 0, len(l.elements))]
+	}},
+}
+
+var tupleMethods = map[string]primitiveMethod[*LoxTuple]{
+	"length": {0, func(name *token.Token, t *LoxTuple, arguments []any) any {
+		return int64(len(t.elements))
+	}},
+	"get": {1, func(name *token.Token, t *LoxTuple, arguments []any) any {
+		return t.elements[indexArgument(name, arguments, 0, len(t.elements))]
+	}},
+}
+
+func stringArgument(name *token.Token, arguments []any, index int) string {
+	s, ok := arguments[index].(string)
+	if !ok {
+		panic(&RuntimeError{
+			token:   name,
+			code:    TYPE_ERROR,
+			message: fmt.Sprintf("Argument %d to '%s' must be a string.", index+1, name.Lexeme),
+		})
+	}
+	return s
+}
+
+func integerArgument(name *token.Token, arguments []any, index int) int {
+	switch n := arguments[index].(type) {
+	case int64:
+		return int(n)
+	case float64:
+		if n == math.Trunc(n) && n >= math.MinInt && n < math.MaxInt {
+			return int(n)
+		}
+	}
+	panic(&RuntimeError{
+		token:   name,
+		code:    TYPE_ERROR,
+		message: fmt.Sprintf("Argument %d to '%s' must be an integer.", index+1, name.Lexeme),
+	})
+}
+
+func indexArgument(name *token.Token, arguments []any, index int, length int) int {
+	i := integerArgument(name, arguments, index)
+	if i < 0 || i >= length {
+		panic(&RuntimeError{
+			token:   name,
+			code:    RANGE_ERROR,
+			message: fmt.Sprintf("Index %d out of bounds for length %d.", i, length),
+		})
+	}
+	return i
+}
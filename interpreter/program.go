@@ -0,0 +1,122 @@
+package interpreter
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/DanielleB-R/golox/interpreter/ast"
+)
+
+// A Lox value as seen from Go: nil, a bool, a string, one of the number
+// types, or one of the interpreter's own types such as *LoxInstance.
+type Value = any
+
+// A Program is source that has been scanned, parsed and resolved, so that it
+// can be run any number of times, by any number of interpreters.
+type Program struct {
+	file       string
+	statements []ast.Stmt
+	tables     *sideTables
+	warnings   []*Warning
+}
+
+// Compiles source, which came from file, or from nowhere in particular if
+// file is empty. Any errors found are returned rather than printed.
+func Compile(file string, source string) (*Program, error) {
+	return compile(file, source, builtins())
+}
+
+// The program is resolved against globals, but the side tables it needs are
+// kept in the program rather than in an interpreter, so that it can be run
+// anywhere.
+func compile(file string, source string, globals *Environment) (*Program, error) {
+	scanner := NewFileScanner(file, source)
+	tokens, err := scanner.ScanTokens()
+	if err != nil {
+		return nil, err
+	}
+
+	parser := NewParser(tokens)
+	statements, err := parser.Parse()
+	if err != nil {
+		return nil, err
+	}
+
+	tables := newSideTables()
+	resolver := newResolver(globals, tables)
+	err = resolver.Resolve(statements)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Program{
+		file:       file,
+		statements: statements,
+		tables:     tables,
+		warnings:   resolver.Warnings(),
+	}, nil
+}
+
+func (p *Program) File() string {
+	return p.file
+}
+
+// The resolver's warnings about the program, sorted by position.
+func (p *Program) Warnings() []*Warning {
+	return p.warnings
+}
+
+// Runs the program, returning the value of its last statement if that is an
+// expression statement, or nil otherwise. The program stops early if ctx is
+// cancelled, as Interpret describes.
+func (i *Interpreter) Run(ctx context.Context, program *Program) (Value, error) {
+	tables := i.tables
+	i.tables = program.tables
+	defer func() { i.tables = tables }()
+	return i.interpret(ctx, program.statements)
+}
+
+// Compiles and runs source, returning the value of its last statement as Run
+// does. Globals defined by the source are kept for later calls.
+func (i *Interpreter) Eval(ctx context.Context, source string) (Value, error) {
+	program, err := compile("", source, i.globals)
+	if err != nil {
+		return nil, err
+	}
+	return i.Run(ctx, program)
+}
+
+// Compiles and runs source for its effects.
+func (i *Interpreter) Exec(source string) error {
+	_, err := i.Eval(context.Background(), source)
+	return err
+}
+
+// Returns the value of a global variable, if there is one called name.
+func (i *Interpreter) Global(name string) (Value, bool) {
+	value, ok := i.globals.values[name]
+	return value, ok
+}
+
+// Calls a Lox function, class or bound method, or a native, from Go. The
+// arguments are converted as ToLox does. A runtime error is returned rather
+// than left to unwind the host, and leaves the interpreter as it was, so
+// Call can be made again, including from inside a native. The call stops
+// early if ctx is cancelled, as Interpret describes.
+func (i *Interpreter) Call(ctx context.Context, callable Value, arguments ...any) (result Value, outerr error) {
+	function, ok := callable.(Callable)
+	if !ok {
+		return nil, &RuntimeError{code: TYPE_ERROR, message: fmt.Sprintf("Can only call functions and classes, not %s.", stringify(callable))}
+	}
+	if len(arguments) != function.Arity() {
+		return nil, &RuntimeError{code: ARITY_ERROR, message: fmt.Sprintf("Expected %d arguments but got %d.", function.Arity(), len(arguments))}
+	}
+
+	values := make([]any, len(arguments))
+	for n, argument := range arguments {
+		values[n] = ToLox(argument)
+	}
+
+	defer i.leave(i.enter(ctx), &outerr)
+	return i.call(function, values, nil), nil
+}
@@ -0,0 +1,248 @@
+package interpreter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvalReturnsLastExpression(t *testing.T) {
+	interpreter := NewInterpreter()
+	value, err := interpreter.Eval(context.Background(), "var a = 2; a * 21;")
+	require.NoError(t, err)
+	require.Equal(t, int64(42), value)
+
+	value, err = interpreter.Eval(context.Background(), "var b = a;")
+	require.NoError(t, err)
+	require.Nil(t, value)
+
+	value, err = interpreter.Eval(context.Background(), `b + 1 == 3;`)
+	require.NoError(t, err)
+	require.Equal(t, true, value)
+}
+
+func TestEvalReturnsErrors(t *testing.T) {
+	interpreter := NewInterpreter()
+
+	_, err := interpreter.Eval(context.Background(), "print ;")
+	var parseErrors ParseErrors
+	require.ErrorAs(t, err, &parseErrors)
+
+	_, err = interpreter.Eval(context.Background(), "missing;")
+	var runtimeError *RuntimeError
+	require.ErrorAs(t, err, &runtimeError)
+
+	require.NoError(t, interpreter.Exec("var fine = true;"))
+	require.Equal(t, true, global(t, interpreter, "fine"))
+}
+
+func TestEvalHonoursCancelledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := NewInterpreter().Eval(ctx, "\n  1 + 2;")
+	require.ErrorIs(t, err, context.Canceled)
+	require.EqualError(t, err, "Runtime error line 2: Interrupted: context canceled.")
+	var runtimeError *RuntimeError
+	require.ErrorAs(t, err, &runtimeError)
+	require.Equal(t, "2:3-2:8", runtimeError.Span().String())
+}
+
+func TestProgramRunsManyTimes(t *testing.T) {
+	program, err := Compile("rules.lox", `
+fun double(n) {
+  var result = n * 2;
+  return result;
+}
+var total = double(21);
+total;
+`)
+	require.NoError(t, err)
+	require.Equal(t, "rules.lox", program.File())
+
+	for range 3 {
+		interpreter := NewInterpreter()
+		value, err := interpreter.Run(context.Background(), program)
+		require.NoError(t, err)
+		require.Equal(t, int64(42), value)
+	}
+}
+
+func TestCompileReportsWarnings(t *testing.T) {
+	program, err := Compile("", "fun f() { var unused = 1; }")
+	require.NoError(t, err)
+	require.Len(t, program.Warnings(), 1)
+	require.Equal(t, UNUSED_VARIABLE, program.Warnings()[0].Category())
+}
+
+func TestCallLoxFromGo(t *testing.T) {
+	interpreter := NewInterpreter()
+	require.NoError(t, interpreter.Exec(`
+var events = 0;
+fun onEvent(e) {
+  events = events + e;
+  return events;
+}
+class Counter {
+  init(start) { this.count = start; }
+  add(n) { this.count = this.count + n; return this; }
+}
+`))
+
+	onEvent, ok := interpreter.Global("onEvent")
+	require.True(t, ok)
+	for _, expected := range []int64{2, 4} {
+		value, err := interpreter.Call(context.Background(), onEvent, 2)
+		require.NoError(t, err)
+		require.Equal(t, expected, value)
+	}
+
+	class, _ := interpreter.Global("Counter")
+	counter, err := interpreter.Call(context.Background(), class, 10)
+	require.NoError(t, err)
+	add, err := counter.(*LoxInstance).Get(tokenNamed("add"))
+	require.NoError(t, err)
+	_, err = interpreter.Call(context.Background(), add, 5)
+	require.NoError(t, err)
+	require.Equal(t, int64(15), counter.(*LoxInstance).fields["count"])
+
+	_, ok = interpreter.Global("missing")
+	require.False(t, ok)
+}
+
+func TestCallReturnsErrorsAndRecovers(t *testing.T) {
+	interpreter := NewInterpreter()
+	require.NoError(t, interpreter.Exec(`
+fun fail(x) {
+  if (x) return nil + 1;
+  return "fine";
+}
+`))
+	fail, _ := interpreter.Global("fail")
+
+	_, err := interpreter.Call(context.Background(), fail, true)
+	var runtimeError *RuntimeError
+	require.ErrorAs(t, err, &runtimeError)
+	require.Equal(t, "in fail, called from Go", runtimeError.StackTrace()[0].String())
+	require.Empty(t, interpreter.frames)
+	require.False(t, interpreter.activeReturn)
+	require.Same(t, interpreter.globals, interpreter.environment)
+
+	value, err := interpreter.Call(context.Background(), fail, false)
+	require.NoError(t, err)
+	require.Equal(t, "fine", value)
+
+	_, err = interpreter.Call(context.Background(), fail)
+	require.EqualError(t, err, "Runtime error: Expected 1 arguments but got 0.")
+	_, err = interpreter.Call(context.Background(), "fail")
+	require.EqualError(t, err, "Runtime error: Can only call functions and classes, not fail.")
+}
+
+func TestCallFromInsideNative(t *testing.T) {
+	interpreter := NewInterpreter()
+	interpreter.DefineNative("twice", 1, func(interpreter *Interpreter, arguments []Value) (Value, error) {
+		if _, err := interpreter.Call(interpreter.Context(), arguments[0]); err != nil {
+			return nil, err
+		}
+		return interpreter.Call(interpreter.Context(), arguments[0])
+	})
+
+	value, err := interpreter.Eval(context.Background(), `
+var n = 0;
+fun bump() { n = n + 1; return n; }
+twice(bump);
+`)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), value)
+
+	_, err = interpreter.Eval(context.Background(), `
+fun broken() { return nil.length(); }
+twice(broken);
+`)
+	var runtimeError *RuntimeError
+	require.ErrorAs(t, err, &runtimeError)
+	require.Len(t, runtimeError.StackTrace(), 2)
+	require.Equal(t, "in twice, called at 3:1", runtimeError.StackTrace()[1].String())
+}
+
+func TestCancellationStopsRunawayScripts(t *testing.T) {
+	cases := map[string]string{
+		"while (true) {}":                       "1:1",
+		"var x = 0; while (true) x = x + 1;":    "1:12",
+		"for (;;) {}":                           "1:1",
+		"fun spin() { return spin(); } spin();": "1:14",
+		"fun f() { while (true) {} } f();":      "1:11",
+	}
+
+	for source, position := range cases {
+		t.Run(source, func(t *testing.T) {
+			interpreter := NewInterpreter()
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+			defer cancel()
+
+			_, err := interpreter.Eval(ctx, source)
+			require.ErrorIs(t, err, context.DeadlineExceeded)
+			var runtimeError *RuntimeError
+			require.ErrorAs(t, err, &runtimeError)
+			require.Equal(t, "Interrupted: context deadline exceeded.", runtimeError.Message())
+			require.Equal(t, position, runtimeError.Span().Start.String())
+
+			require.Empty(t, interpreter.frames)
+			require.Same(t, interpreter.globals, interpreter.environment)
+			value, err := interpreter.Eval(context.Background(), "1 + 1;")
+			require.NoError(t, err)
+			require.Equal(t, int64(2), value)
+		})
+	}
+}
+
+func TestRunKeepsSideTablesWithTheProgram(t *testing.T) {
+	interpreter := NewInterpreter()
+	require.NoError(t, interpreter.Exec(`
+fun counter() {
+  var n = 0;
+  fun next() { n = n + 1; return n; }
+  return next;
+}
+var next = counter();
+`))
+	require.NoError(t, interpreter.Exec("next();"))
+
+	value, err := interpreter.Eval(context.Background(), `
+fun loop(n) { while (true) { if (n > 2) break; n = n + 1; } return next(); }
+loop(0);
+`)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), value)
+
+	require.Empty(t, interpreter.tables.locals)
+	require.Empty(t, interpreter.tables.tailCalls)
+	require.Empty(t, interpreter.tables.jumps)
+}
+
+func TestCallHonoursCancelledContext(t *testing.T) {
+	interpreter := NewInterpreter()
+	require.NoError(t, interpreter.Exec(`
+fun spin() { while (true) {} }
+fun recurse() { return recurse(); }
+`))
+
+	for _, name := range []string{"spin", "recurse"} {
+		function, _ := interpreter.Global(name)
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		_, err := interpreter.Call(ctx, function)
+		cancel()
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		require.Equal(t, context.Background(), interpreter.Context())
+		require.Empty(t, interpreter.frames)
+	}
+
+	interpreter.DefineNative("wait", 1, func(interpreter *Interpreter, arguments []Value) (Value, error) {
+		return interpreter.Call(interpreter.Context(), arguments[0])
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := interpreter.Eval(ctx, "wait(spin);")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
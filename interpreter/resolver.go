@@ -1,6 +1,10 @@
 package interpreter
 
 import (
+	"cmp"
+	"slices"
+	"strings"
+
 	"github.com/DanielleB-R/golox/interpreter/ast"
 	"github.com/DanielleB-R/golox/interpreter/token"
 )
@@ -27,47 +31,119 @@ const (
 	SUBCLASS
 )
 
-type Scope = map[string]bool
+// What the resolver knows about a local variable. The name is nil for the
+// implicit this and super.
+type local struct {
+	name      *token.Token
+	defined   bool
+	used      bool
+	parameter bool
+}
+
+type Scope = map[string]*local
+
+// What the resolver works out about a program, for the interpreter to use
+// when it runs it.
+type sideTables struct {
+	locals    map[ast.Expr]int
+	tailCalls map[*ast.Call]bool
+	jumps     map[ast.Stmt]*ast.While
+}
+
+func newSideTables() *sideTables {
+	return &sideTables{
+		locals:    map[ast.Expr]int{},
+		tailCalls: map[*ast.Call]bool{},
+		jumps:     map[ast.Stmt]*ast.While{},
+	}
+}
+
+func (t *sideTables) resolve(expr ast.Expr, depth int) {
+	t.locals[expr] = depth
+}
+
+func (t *sideTables) resolveTailCall(call *ast.Call) {
+	t.tailCalls[call] = true
+}
+
+func (t *sideTables) resolveJump(stmt ast.Stmt, loop *ast.While) {
+	t.jumps[stmt] = loop
+}
 
 type Resolver struct {
-	interpreter     *Interpreter
+	// Globals already defined, which code is resolved against
+	environment     *Environment
+	tables          *sideTables
 	scopes          []Scope
 	currentFunction FunctionType
 	currentClass    ClassType
+	loops           []*ast.While
+	globals         map[string]bool
+	errors          ResolverErrors
+	warnings        []*Warning
 }
 
+// Resolves code for interpreter to run with Interpret.
 func NewResolver(interpreter *Interpreter) *Resolver {
+	return newResolver(interpreter.globals, interpreter.tables)
+}
+
+func newResolver(globals *Environment, tables *sideTables) *Resolver {
 	return &Resolver{
-		interpreter:     interpreter,
+		environment:     globals,
+		tables:          tables,
 		scopes:          nil,
 		currentFunction: NO_FUNCTION,
 		currentClass:    NO_CLASS,
+		loops:           nil,
+		globals:         map[string]bool{},
+		errors:          ResolverErrors{},
+		warnings:        []*Warning{},
 	}
 }
 
-func (r *Resolver) Resolve(statements []ast.Stmt) (err error) {
-	defer func() {
-		recovered := recover()
-		if recovered == nil {
-			return
-		}
-		if readerErr, ok := recovered.(*ResolverError); ok {
-			err = readerErr
-			return
-		}
-		panic(recovered)
-	}()
-
+// Resolution carries on past errors, so that every one of them is reported.
+// They are returned sorted by position.
+func (r *Resolver) Resolve(statements []ast.Stmt) error {
 	r.resolveStmts(statements)
+	if len(r.errors) > 0 {
+		slices.SortStableFunc(r.errors, func(a, b *ResolverError) int {
+			return cmp.Or(cmp.Compare(a.token.Start().Line, b.token.Start().Line), cmp.Compare(a.token.Column, b.token.Column))
+		})
+		return r.errors
+	}
 	return nil
 }
 
+// Returns everything that looked suspicious during resolution, sorted by
+// position. Warnings don't stop a program from running.
+func (r *Resolver) Warnings() []*Warning {
+	sortWarnings(r.warnings)
+	return r.warnings
+}
+
 func (r *Resolver) resolveStmts(statements []ast.Stmt) {
-	for _, statement := range statements {
+	for n, statement := range statements {
 		r.resolveStmt(statement)
+		if keyword := jumpKeyword(statement); keyword != nil && n < len(statements)-1 {
+			r.warn(UNREACHABLE_CODE, keyword.Span(), "Code after '"+keyword.Lexeme+"' is never run")
+		}
 	}
 }
 
+// Returns the keyword of a statement that always leaves the block it is in.
+func jumpKeyword(stmt ast.Stmt) *token.Token {
+	switch stmt := stmt.(type) {
+	case *ast.Return:
+		return stmt.Keyword
+	case *ast.Break:
+		return stmt.Keyword
+	case *ast.Continue:
+		return stmt.Keyword
+	}
+	return nil
+}
+
 func (r *Resolver) resolveStmt(stmt ast.Stmt) {
 	stmt.Accept(r)
 }
@@ -76,12 +152,20 @@ func (r *Resolver) resolveExpr(expr ast.Expr) {
 	expr.Accept(r)
 }
 
+// Bad nodes only appear in partial ASTs, which have parse errors of their
+// own, so there is nothing more to say about them here.
+func (r *Resolver) VisitBadStmt(stmt *ast.BadStmt) {}
+
 func (r *Resolver) VisitBlock(stmt *ast.Block) {
 	r.beginScope()
 	defer r.endScope()
 	r.resolveStmts(stmt.Statements)
 }
 
+func (r *Resolver) VisitBreak(stmt *ast.Break) {
+	r.resolveJump(stmt, stmt.Keyword, stmt.Label)
+}
+
 func (r *Resolver) VisitClass(stmt *ast.Class) {
 	enclosingClass := r.currentClass
 	defer func() { r.currentClass = enclosingClass }()
@@ -92,18 +176,18 @@ func (r *Resolver) VisitClass(stmt *ast.Class) {
 
 	if stmt.Superclass != nil {
 		if stmt.Name.Lexeme == stmt.Superclass.Name.Lexeme {
-			panic(&ResolverError{token: stmt.Name, message: "A class cannot inherit from itself"})
+			r.error(stmt.Name, SELF_INHERITANCE_ERROR, "A class cannot inherit from itself")
 		}
 		r.currentClass = SUBCLASS
 		r.resolveExpr(stmt.Superclass)
 		r.beginScope()
 		defer r.endScope()
-		r.scopes[len(r.scopes)-1]["super"] = true
+		r.scopes[len(r.scopes)-1]["super"] = &local{defined: true}
 	}
 
 	r.beginScope()
 	defer r.endScope()
-	r.scopes[len(r.scopes)-1]["this"] = true
+	r.scopes[len(r.scopes)-1]["this"] = &local{defined: true}
 
 	for _, method := range stmt.Methods {
 		declaration := METHOD
@@ -114,6 +198,10 @@ func (r *Resolver) VisitClass(stmt *ast.Class) {
 	}
 }
 
+func (r *Resolver) VisitContinue(stmt *ast.Continue) {
+	r.resolveJump(stmt, stmt.Keyword, stmt.Label)
+}
+
 func (r *Resolver) VisitExpressionStmt(stmt *ast.ExpressionStmt) {
 	r.resolveExpr(stmt.Expression)
 }
@@ -133,21 +221,31 @@ func (r *Resolver) VisitIf(stmt *ast.If) {
 	}
 }
 
+func (r *Resolver) VisitMatch(stmt *ast.Match) {
+	r.resolveExpr(stmt.Subject)
+	for _, matchCase := range stmt.Cases {
+		r.resolveMatchCase(matchCase)
+	}
+}
+
 func (r *Resolver) VisitPrint(stmt *ast.Print) {
 	r.resolveExpr(stmt.Expression)
 }
 
 func (r *Resolver) VisitReturn(stmt *ast.Return) {
 	if r.currentFunction == NO_FUNCTION {
-		panic(&ResolverError{token: stmt.Keyword, message: "Can't return from top-level code"})
+		r.error(stmt.Keyword, MISPLACED_KEYWORD_ERROR, "Can't return from top-level code")
 	}
 
 	if stmt.Value != nil {
 		if r.currentFunction == INITIALIZER {
-			panic(&ResolverError{token: stmt.Keyword, message: "Can't return a value from an initializer"})
+			r.error(stmt.Keyword, MISPLACED_KEYWORD_ERROR, "Can't return a value from an initializer")
 		}
 
 		r.resolveExpr(stmt.Value)
+		if call, ok := stmt.Value.(*ast.Call); ok {
+			r.tables.resolveTailCall(call)
+		}
 	}
 }
 
@@ -158,9 +256,26 @@ func (r *Resolver) VisitVar(stmt *ast.Var) {
 	}
 	r.define(stmt.Name)
 }
+
+func (r *Resolver) VisitVarDestructure(stmt *ast.VarDestructure) {
+	r.resolveExpr(stmt.Initializer)
+	r.resolvePattern(stmt.Pattern)
+}
+
 func (r *Resolver) VisitWhile(stmt *ast.While) {
+	if stmt.Label != nil && r.findLoop(stmt.Label.Lexeme) != nil {
+		r.error(stmt.Label, DUPLICATE_NAME_ERROR, "Label '"+stmt.Label.Lexeme+"' is already used by an enclosing loop")
+	}
+
 	r.resolveExpr(stmt.Condition)
+
+	r.loops = append(r.loops, stmt)
+	defer func() { r.loops = r.loops[0:(len(r.loops) - 1)] }()
 	r.resolveStmt(stmt.Body)
+
+	if stmt.Increment != nil {
+		r.resolveExpr(stmt.Increment)
+	}
 }
 
 func (r *Resolver) VisitAssign(expr *ast.Assign) any {
@@ -169,7 +284,19 @@ func (r *Resolver) VisitAssign(expr *ast.Assign) any {
 	return nil
 }
 
+func (r *Resolver) VisitBadExpr(expr *ast.BadExpr) any {
+	return nil
+}
+
 func (r *Resolver) VisitBinary(expr *ast.Binary) any {
+	switch expr.Operator.TokenType {
+	case token.EQUAL_EQUAL, token.BANG_EQUAL, token.LESS, token.LESS_EQUAL, token.GREATER, token.GREATER_EQUAL:
+		if sameReference(expr.Left, expr.Right) {
+			printed := (&ast.AstPrinter{}).Print(expr.Left)
+			r.warn(SELF_COMPARISON, ast.SpanOf(expr), "Comparing '"+printed+"' with itself")
+		}
+	}
+
 	r.resolveExpr(expr.Left)
 	r.resolveExpr(expr.Right)
 	return nil
@@ -212,10 +339,9 @@ func (r *Resolver) VisitSet(expr *ast.Set) any {
 
 func (r *Resolver) VisitSuper(expr *ast.Super) any {
 	if r.currentClass == NO_CLASS {
-		panic(&ResolverError{token: expr.Keyword, message: "Cannot use 'super' outside of a class"})
-	}
-	if r.currentClass != SUBCLASS {
-		panic(&ResolverError{token: expr.Keyword, message: "Can't use 'super' in a class with no superclasses"})
+		r.error(expr.Keyword, MISPLACED_KEYWORD_ERROR, "Cannot use 'super' outside of a class")
+	} else if r.currentClass != SUBCLASS {
+		r.error(expr.Keyword, MISPLACED_KEYWORD_ERROR, "Can't use 'super' in a class with no superclasses")
 	}
 	r.resolveLocal(expr, expr.Keyword)
 	return nil
@@ -223,13 +349,20 @@ func (r *Resolver) VisitSuper(expr *ast.Super) any {
 
 func (r *Resolver) VisitThis(expr *ast.This) any {
 	if r.currentClass == NO_CLASS {
-		panic(&ResolverError{token: expr.Keyword, message: "Cannot use 'this' outside of a class"})
+		r.error(expr.Keyword, MISPLACED_KEYWORD_ERROR, "Cannot use 'this' outside of a class")
 	}
 
 	r.resolveLocal(expr, expr.Keyword)
 	return nil
 }
 
+func (r *Resolver) VisitTuple(expr *ast.Tuple) any {
+	for _, element := range expr.Elements {
+		r.resolveExpr(element)
+	}
+	return nil
+}
+
 func (r *Resolver) VisitUnary(expr *ast.Unary) any {
 	r.resolveExpr(expr.Right)
 	return nil
@@ -237,12 +370,14 @@ func (r *Resolver) VisitUnary(expr *ast.Unary) any {
 
 func (r *Resolver) VisitVariable(expr *ast.Variable) any {
 	if len(r.scopes) > 0 {
-		if value, ok := r.scopes[len(r.scopes)-1][expr.Name.Lexeme]; ok && !value {
-			panic(&ResolverError{token: expr.Name, message: "Can't read local variable in its own initializer"})
+		if variable, ok := r.scopes[len(r.scopes)-1][expr.Name.Lexeme]; ok && !variable.defined {
+			r.error(expr.Name, OWN_INITIALIZER_ERROR, "Can't read local variable in its own initializer")
 		}
 	}
 
-	r.resolveLocal(expr, expr.Name)
+	if variable := r.resolveLocal(expr, expr.Name); variable != nil {
+		variable.used = true
+	}
 	return nil
 }
 
@@ -250,21 +385,34 @@ func (r *Resolver) beginScope() {
 	r.scopes = append(r.scopes, Scope{})
 }
 
+// Locals that are never read are reported as the scope closes. Parameters
+// and names starting with '_' are left alone, since they often have to be
+// there even when they aren't needed.
 func (r *Resolver) endScope() {
+	for _, variable := range r.scopes[len(r.scopes)-1] {
+		if variable.name == nil || variable.used || variable.parameter || strings.HasPrefix(variable.name.Lexeme, "_") {
+			continue
+		}
+		r.warn(UNUSED_VARIABLE, variable.name.Span(), "Local '"+variable.name.Lexeme+"' is never read")
+	}
 	r.scopes = r.scopes[0:(len(r.scopes) - 1)]
 }
 
-func (r *Resolver) declare(name *token.Token) {
+// Returns the local for the name, or nil when it is a global.
+func (r *Resolver) declare(name *token.Token) *local {
 	if len(r.scopes) == 0 {
-		return
+		r.globals[name.Lexeme] = true
+		return nil
 	}
 
 	scope := r.scopes[len(r.scopes)-1]
 	if _, ok := scope[name.Lexeme]; ok {
-		panic(&ResolverError{token: name, message: "Already a variable with this name in this scope"})
+		r.error(name, DUPLICATE_NAME_ERROR, "Already a variable with this name in this scope")
 	}
 
-	scope[name.Lexeme] = false
+	variable := &local{name: name}
+	scope[name.Lexeme] = variable
+	return variable
 }
 
 func (r *Resolver) define(name *token.Token) {
@@ -272,16 +420,112 @@ func (r *Resolver) define(name *token.Token) {
 		return
 	}
 
-	r.scopes[len(r.scopes)-1][name.Lexeme] = true
+	r.scopes[len(r.scopes)-1][name.Lexeme].defined = true
 }
 
-func (r *Resolver) resolveLocal(expr ast.Expr, name *token.Token) {
+// Returns the local the name refers to, or nil when it is a global.
+func (r *Resolver) resolveLocal(expr ast.Expr, name *token.Token) *local {
 	for i := len(r.scopes) - 1; i >= 0; i-- {
-		if _, ok := r.scopes[i][name.Lexeme]; ok {
-			r.interpreter.resolve(expr, len(r.scopes)-1-i)
+		if variable, ok := r.scopes[i][name.Lexeme]; ok {
+			r.tables.resolve(expr, len(r.scopes)-1-i)
+			return variable
+		}
+	}
+	return nil
+}
+
+// Whether the name is already a variable outside of the innermost scope,
+// either a local or a global declared so far.
+func (r *Resolver) isOuterVariable(name string) bool {
+	for i := len(r.scopes) - 2; i >= 0; i-- {
+		if _, ok := r.scopes[i][name]; ok {
+			return true
+		}
+	}
+	if r.globals[name] {
+		return true
+	}
+	_, ok := r.environment.values[name]
+	return ok
+}
+
+// Each case gets its own scope holding the variables bound by its pattern,
+// mirroring the environment the interpreter creates for it.
+func (r *Resolver) resolveMatchCase(matchCase *ast.MatchCase) {
+	r.beginScope()
+	defer r.endScope()
+
+	for _, pattern := range matchCase.Patterns {
+		if r.resolvePattern(pattern) && len(matchCase.Patterns) > 1 {
+			r.error(matchCase.Keyword, ALTERNATIVE_BINDING_ERROR, "Alternative patterns can't bind variables")
+			break
+		}
+	}
+	if matchCase.Guard != nil {
+		r.resolveExpr(matchCase.Guard)
+	}
+	r.resolveStmt(matchCase.Body)
+}
+
+// Returns whether the pattern binds any variables.
+func (r *Resolver) resolvePattern(pattern ast.Pattern) bool {
+	switch pattern := pattern.(type) {
+	case *ast.BindingPattern:
+		r.declare(pattern.Name)
+		r.define(pattern.Name)
+		return true
+	case *ast.ClassPattern:
+		r.resolveExpr(pattern.Class)
+		binds := false
+		for _, field := range pattern.Fields {
+			binds = r.resolvePattern(field.Pattern) || binds
+		}
+		return binds
+	case *ast.ListPattern:
+		binds := false
+		for _, element := range pattern.Elements {
+			binds = r.resolvePattern(element) || binds
+		}
+		return binds
+	case *ast.TuplePattern:
+		binds := false
+		for _, element := range pattern.Elements {
+			binds = r.resolvePattern(element) || binds
+		}
+		return binds
+	case *ast.RestPattern:
+		return r.resolvePattern(pattern.Binding)
+	}
+	return false
+}
+
+// Jumps bind to the innermost loop, or to the innermost loop with a matching
+// label, and never across a function boundary.
+func (r *Resolver) resolveJump(stmt ast.Stmt, keyword *token.Token, label *token.Token) {
+	if len(r.loops) == 0 {
+		r.error(keyword, MISPLACED_KEYWORD_ERROR, "Can't use '"+keyword.Lexeme+"' outside of a loop")
+		return
+	}
+
+	loop := r.loops[len(r.loops)-1]
+	if label != nil {
+		loop = r.findLoop(label.Lexeme)
+		if loop == nil {
+			r.error(label, UNDEFINED_LABEL_ERROR, "No enclosing loop labeled '"+label.Lexeme+"'")
 			return
 		}
 	}
+
+	r.tables.resolveJump(stmt, loop)
+}
+
+func (r *Resolver) findLoop(label string) *ast.While {
+	for i := len(r.loops) - 1; i >= 0; i-- {
+		if r.loops[i].Label != nil && r.loops[i].Label.Lexeme == label {
+			return r.loops[i]
+		}
+	}
+	return nil
 }
 
 func (r *Resolver) resolveFunction(stmt *ast.Function, functionType FunctionType) {
@@ -289,12 +533,50 @@ func (r *Resolver) resolveFunction(stmt *ast.Function, functionType FunctionType
 	r.currentFunction = functionType
 	defer func() { r.currentFunction = previousFunctionType }()
 
+	enclosingLoops := r.loops
+	r.loops = nil
+	defer func() { r.loops = enclosingLoops }()
+
 	r.beginScope()
 	defer r.endScope()
 	for _, param := range stmt.Params {
-		r.declare(param)
+		r.declare(param).parameter = true
 		r.define(param)
-
+		if r.isOuterVariable(param.Lexeme) {
+			r.warn(SHADOWED_VARIABLE, param.Span(), "Parameter '"+param.Lexeme+"' shadows an outer variable")
+		}
 	}
 	r.resolveStmts(stmt.Body)
 }
+
+func (r *Resolver) error(tok *token.Token, code string, message string) {
+	r.errors = append(r.errors, &ResolverError{token: tok, code: code, message: message})
+}
+
+func (r *Resolver) warn(category WarningCategory, span token.Span, message string) {
+	r.warnings = append(r.warnings, &Warning{category: category, span: span, message: message})
+}
+
+// Whether two expressions certainly refer to the same value: the same
+// variable, or the same chain of fields on one.
+func sameReference(left ast.Expr, right ast.Expr) bool {
+	if grouping, ok := left.(*ast.Grouping); ok {
+		return sameReference(grouping.Expression, right)
+	}
+	if grouping, ok := right.(*ast.Grouping); ok {
+		return sameReference(left, grouping.Expression)
+	}
+
+	switch left := left.(type) {
+	case *ast.Variable:
+		right, ok := right.(*ast.Variable)
+		return ok && left.Name.Lexeme == right.Name.Lexeme
+	case *ast.This:
+		_, ok := right.(*ast.This)
+		return ok
+	case *ast.Get:
+		right, ok := right.(*ast.Get)
+		return ok && left.Name.Lexeme == right.Name.Lexeme && sameReference(left.Object, right.Object)
+	}
+	return false
+}
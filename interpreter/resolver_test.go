@@ -0,0 +1,122 @@
+package interpreter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func resolve(t *testing.T, source string) error {
+	tokens, err := scan(source)
+	require.NoError(t, err)
+	statements, err := NewParser(tokens).Parse()
+	require.NoError(t, err)
+	return NewResolver(NewInterpreter()).Resolve(statements)
+}
+
+func TestResolverReportsEveryError(t *testing.T) {
+	err := resolve(t, `
+return 1;
+fun f() {
+  var a = 1; var a = 2;
+  { var b = b; }
+  break;
+}
+class A < A { init() { return 1; } }
+print this; print super.x;
+while (true) { continue outer; }
+`)
+	require.Error(t, err)
+
+	messages := []string{}
+	for _, e := range err.(ResolverErrors) {
+		messages = append(messages, e.Error())
+	}
+	require.Equal(t, []string{
+		"Name resolution error line 2: Can't return from top-level code",
+		"Name resolution error line 4: Already a variable with this name in this scope",
+		"Name resolution error line 5: Can't read local variable in its own initializer",
+		"Name resolution error line 6: Can't use 'break' outside of a loop",
+		"Name resolution error line 8: A class cannot inherit from itself",
+		"Name resolution error line 8: Can't return a value from an initializer",
+		"Name resolution error line 9: Cannot use 'this' outside of a class",
+		"Name resolution error line 9: Cannot use 'super' outside of a class",
+		"Name resolution error line 10: No enclosing loop labeled 'outer'",
+	}, messages)
+}
+
+func TestResolverErrorsAreSortedByPosition(t *testing.T) {
+	// The duplicate binding is found before the alternatives are checked, but
+	// the case keyword comes first in the source.
+	err := resolve(t, `match (1) { case [x, x], 2 => print x; }`)
+	require.Error(t, err)
+
+	messages := []string{}
+	for _, e := range err.(ResolverErrors) {
+		messages = append(messages, e.message)
+	}
+	require.Equal(t, []string{
+		"Alternative patterns can't bind variables",
+		"Already a variable with this name in this scope",
+	}, messages)
+}
+
+func warningsFor(t *testing.T, source string) []string {
+	tokens, err := scan(source)
+	require.NoError(t, err)
+	statements, err := NewParser(tokens).Parse()
+	require.NoError(t, err)
+	resolver := NewResolver(NewInterpreter())
+	require.NoError(t, resolver.Resolve(statements))
+
+	warnings := []string{}
+	for _, warning := range resolver.Warnings() {
+		warnings = append(warnings, warning.String())
+	}
+	return warnings
+}
+
+func TestResolverWarnings(t *testing.T) {
+	warnings := warningsFor(t, `
+var limit = 10;
+fun check(limit, unusedParameter) {
+  var count = 0;
+  var _ignored = 1;
+  fun helper() {}
+  count = 1;
+  if (limit <= (limit)) print limit.x == limit.y;
+  return limit;
+  print "never";
+}
+while (true) {
+  break;
+  continue;
+}
+{ var a = 1; print a == a.b; }
+`)
+	require.Equal(t, []string{
+		"Warning line 3: Parameter 'limit' shadows an outer variable [shadow]",
+		"Warning line 4: Local 'count' is never read [unused]",
+		"Warning line 6: Local 'helper' is never read [unused]",
+		"Warning line 8: Comparing 'limit' with itself [self-compare]",
+		"Warning line 9: Code after 'return' is never run [unreachable]",
+		"Warning line 13: Code after 'break' is never run [unreachable]",
+	}, warnings)
+}
+
+func TestShadowingNativeIsAWarning(t *testing.T) {
+	require.Equal(t, []string{
+		"Warning line 1: Parameter 'clock' shadows an outer variable [shadow]",
+	}, warningsFor(t, `fun f(clock) { return clock; }`))
+}
+
+func TestWarningSetApply(t *testing.T) {
+	warnings := AllWarnings()
+	require.NoError(t, warnings.Apply("all,no-shadow"))
+	require.Equal(t, WarningSet{UNUSED_VARIABLE: true, SHADOWED_VARIABLE: false, UNREACHABLE_CODE: true, SELF_COMPARISON: true}, warnings)
+
+	require.NoError(t, warnings.Apply("none, unreachable"))
+	require.Equal(t, WarningSet{UNREACHABLE_CODE: true}, warnings)
+
+	require.EqualError(t, warnings.Apply("no-typos"), "unknown warning category 'typos'; expected one of unused, shadow, unreachable, self-compare")
+}
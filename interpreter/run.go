@@ -1,58 +1,94 @@
 package interpreter
 
 import (
-	"bufio"
+	"context"
 	"fmt"
+	"io"
 	"os"
 )
 
-func RunFile(path string) {
+type RunOptions struct {
+	// Warnings in these categories are printed; the rest are dropped.
+	Warnings WarningSet
+	// Errors and warnings are written as JSON, one object per line, rather
+	// than as text.
+	JSONDiagnostics bool
+}
+
+func DefaultRunOptions() RunOptions {
+	return RunOptions{Warnings: AllWarnings()}
+}
+
+// The interpreter is made with the options given, so that its output can be
+// sent elsewhere.
+func RunFile(path string, options RunOptions, interpreterOptions ...Option) {
+	interpreter := NewInterpreter(interpreterOptions...)
 	script, err := os.ReadFile(path)
 	if err != nil {
-		fmt.Fprintln(os.Stderr, "Error reading file", path)
+		fmt.Fprintln(interpreter.stderr, "Error reading file", path)
 		os.Exit(1)
 	}
-	err = run(string(script), NewInterpreter())
+	renderer := options.renderer(string(script), interpreter.stderr)
+	err = runSource(path, string(script), interpreter, options.reporter(renderer, interpreter.stderr))
 
 	if err != nil {
-		fmt.Fprintln(os.Stderr, err)
+		renderer.Render(interpreter.stderr, err)
 		os.Exit(65)
 	}
 }
 
-func RunPrompt() {
-	scanner := bufio.NewScanner(os.Stdin)
-	interpreter := NewInterpreter()
+// The prompt is written to the interpreter's output and lines are read from
+// its input.
+func RunPrompt(options RunOptions, interpreterOptions ...Option) {
+	interpreter := NewInterpreter(interpreterOptions...)
 	for {
-		fmt.Print("> ")
-		if !scanner.Scan() {
+		fmt.Fprint(interpreter.stdout, "> ")
+		line, ok := interpreter.readLine()
+		if !ok {
 			break
 		}
-		err := run(scanner.Text(), interpreter)
+		renderer := options.renderer(line, interpreter.stderr)
+		err := runSource("", line, interpreter, options.reporter(renderer, interpreter.stderr))
 		if err != nil {
-			fmt.Fprintln(os.Stderr, err)
+			renderer.Render(interpreter.stderr, err)
 		}
 	}
 }
 
-func run(source string, interpreter *Interpreter) error {
-	scanner := NewSourceScanner(source)
-	tokens, err := scanner.ScanTokens()
-	if err != nil {
-		return err
+// Colour is only used when w is a terminal.
+func (o RunOptions) renderer(source string, w io.Writer) Renderer {
+	if o.JSONDiagnostics {
+		return &JSONRenderer{}
 	}
+	f, ok := w.(*os.File)
+	return NewDiagnosticRenderer(source, ok && UseColour(f))
+}
 
-	parser := NewParser(tokens)
-	statements, err := parser.Parse()
-	if err != nil {
-		return err
+// Returns a function that prints the warnings the options ask for to w.
+func (o RunOptions) reporter(renderer Renderer, w io.Writer) func(*Warning) {
+	return func(warning *Warning) {
+		if o.Warnings[warning.Category()] {
+			renderer.RenderWarning(w, warning)
+		}
 	}
+}
+
+func run(source string, interpreter *Interpreter) error {
+	return runSource("", source, interpreter, nil)
+}
 
-	resolver := NewResolver(interpreter)
-	err = resolver.Resolve(statements)
+// Warnings are passed to warn, if it isn't nil, before the program runs.
+func runSource(file string, source string, interpreter *Interpreter, warn func(*Warning)) error {
+	program, err := compile(file, source, interpreter.globals)
 	if err != nil {
 		return err
 	}
+	if warn != nil {
+		for _, warning := range program.Warnings() {
+			warn(warning)
+		}
+	}
 
-	return interpreter.Interpret(statements)
+	_, err = interpreter.Run(context.Background(), program)
+	return err
 }
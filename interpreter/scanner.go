@@ -1,77 +1,150 @@
 package interpreter
 
 import (
+	"fmt"
+	"math/big"
 	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/DanielleB-R/golox/interpreter/token"
 )
 
+// The scanner works in runes rather than bytes. start and current are byte
+// offsets into source, while columns count runes from 1.
 type SourceScanner struct {
+	file   string
 	source string
 	tokens []*token.Token
 
-	start   int
-	current int
-	line    int
-	errors  SourceErrors
+	start       int
+	current     int
+	startLine   int
+	line        int
+	startColumn int
+	column      int
+	trivia      []*token.Trivia
+	errors      SourceErrors
 }
 
 func NewSourceScanner(source string) SourceScanner {
-	return SourceScanner{source: source, tokens: []*token.Token{}, start: 0, current: 0, line: 1, errors: SourceErrors{}}
+	return NewFileScanner("", source)
+}
+
+// The file name is recorded in every token and error, so that errors from
+// different files can be told apart.
+func NewFileScanner(file string, source string) SourceScanner {
+	return SourceScanner{file: file, source: source, tokens: []*token.Token{}, start: 0, current: 0, startLine: 1, line: 1, startColumn: 1, column: 1, errors: SourceErrors{}}
 }
 
 func (s *SourceScanner) ScanTokens() ([]*token.Token, error) {
 	for !s.isAtEnd() {
-		s.start = s.current
+		s.startLexeme()
 		s.scanToken()
 	}
 
-	s.tokens = append(s.tokens, token.NewToken(token.EOF, "", nil, s.line))
+	s.startLexeme()
+	s.addToken(token.EOF, nil)
 	if len(s.errors) > 0 {
 		return nil, s.errors
 	}
 	return s.tokens, nil
 }
 
+func (s *SourceScanner) startLexeme() {
+	s.start = s.current
+	s.startLine = s.line
+	s.startColumn = s.column
+}
+
+func (s *SourceScanner) startPosition() token.Position {
+	return token.Position{File: s.file, Offset: s.start, Line: s.startLine, Column: s.startColumn}
+}
+
+func (s *SourceScanner) position() token.Position {
+	return token.Position{File: s.file, Offset: s.current, Line: s.line, Column: s.column}
+}
+
 func (s *SourceScanner) isAtEnd() bool {
 	return s.current >= len(s.source)
 }
 
-func (s *SourceScanner) advance() byte {
-	r := s.source[s.current]
-	s.current += 1
+func (s *SourceScanner) advance() rune {
+	r, size := utf8.DecodeRuneInString(s.source[s.current:])
+	s.current += size
+	if r == '\n' {
+		s.line += 1
+		s.column = 1
+	} else {
+		s.column += 1
+	}
 	return r
 }
 
-func (s *SourceScanner) match(expected byte) bool {
+func (s *SourceScanner) match(expected rune) bool {
 	if s.isAtEnd() {
 		return false
 	}
-	if s.source[s.current] != expected {
+	if s.peek() != expected {
 		return false
 	}
 
-	s.current += 1
+	s.advance()
 	return true
 }
 
-func (s *SourceScanner) peek() byte {
+func (s *SourceScanner) peek() rune {
 	if s.isAtEnd() {
 		return 0
 	}
-	return s.source[s.current]
+	r, _ := utf8.DecodeRuneInString(s.source[s.current:])
+	return r
 }
 
-func (s *SourceScanner) peekNext() byte {
-	if (s.current + 1) >= len(s.source) {
+func (s *SourceScanner) peekNext() rune {
+	if s.isAtEnd() {
 		return 0
 	}
-	return s.source[s.current+1]
+	_, size := utf8.DecodeRuneInString(s.source[s.current:])
+	if s.current+size >= len(s.source) {
+		return 0
+	}
+	r, _ := utf8.DecodeRuneInString(s.source[s.current+size:])
+	return r
 }
 
 func (s *SourceScanner) addToken(tokenType int, literal any) {
 	text := s.source[s.start:s.current]
-	s.tokens = append(s.tokens, token.NewToken(tokenType, text, literal, s.line))
+	newToken := token.NewToken(tokenType, text, literal, s.line)
+	newToken.File = s.file
+	newToken.Offset = s.start
+	newToken.Column = s.startColumn
+	newToken.End = s.position()
+	newToken.LeadingTrivia = s.trivia
+	s.trivia = nil
+	s.tokens = append(s.tokens, newToken)
+}
+
+func (s *SourceScanner) addTrivia(kind int) {
+	s.trivia = append(s.trivia, &token.Trivia{
+		Kind:   kind,
+		Text:   s.source[s.start:s.current],
+		Line:   s.startLine,
+		Column: s.startColumn,
+	})
+}
+
+// Errors cover the lexeme scanned so far.
+func (s *SourceScanner) addError(code string, message string, notes ...string) {
+	s.addErrorAt(token.Span{Start: s.startPosition(), End: s.position()}, code, message, notes...)
+}
+
+func (s *SourceScanner) addErrorAt(span token.Span, code string, message string, notes ...string) {
+	err := NewSourceError(span, "", message)
+	err.code = code
+	err.notes = notes
+	s.errors = append(s.errors, err)
 }
 
 func (s *SourceScanner) scanToken() {
@@ -86,10 +159,24 @@ func (s *SourceScanner) scanToken() {
 		s.addToken(token.LEFT_BRACE, nil)
 	case '}':
 		s.addToken(token.RIGHT_BRACE, nil)
+	case '[':
+		s.addToken(token.LEFT_BRACKET, nil)
+	case ']':
+		s.addToken(token.RIGHT_BRACKET, nil)
+	case ':':
+		s.addToken(token.COLON, nil)
 	case ',':
 		s.addToken(token.COMMA, nil)
 	case '.':
-		s.addToken(token.DOT, nil)
+		if s.peek() == '.' && s.peekNext() == '.' {
+			s.advance()
+			s.advance()
+			s.addToken(token.ELLIPSIS, nil)
+		} else if isDigit(s.peek()) {
+			s.number()
+		} else {
+			s.addToken(token.DOT, nil)
+		}
 	case '-':
 		s.addToken(token.MINUS, nil)
 	case '+':
@@ -108,6 +195,8 @@ func (s *SourceScanner) scanToken() {
 		if s.match('=') {
 			s.addToken(token.EQUAL_EQUAL, nil)
 
+		} else if s.match('>') {
+			s.addToken(token.ARROW, nil)
 		} else {
 			s.addToken(token.EQUAL, nil)
 		}
@@ -126,17 +215,14 @@ func (s *SourceScanner) scanToken() {
 		}
 	case '/':
 		if s.match('/') {
-			// Skip comment, which goes to the end of the line
-			for s.peek() != '\n' && !s.isAtEnd() {
-				s.advance()
-			}
+			s.lineComment()
+		} else if s.match('*') {
+			s.blockComment()
 		} else {
 			s.addToken(token.SLASH, nil)
 		}
-	case ' ', '\r', '\t':
+	case ' ', '\r', '\t', '\n':
 		break
-	case '\n':
-		s.line += 1
 	case '"':
 		s.string()
 	default:
@@ -145,21 +231,57 @@ func (s *SourceScanner) scanToken() {
 		} else if isAlpha(c) {
 			s.identifier()
 		} else {
-			s.errors = append(s.errors, NewSourceError(s.line, "", "Unexpected character."))
+			s.addError(UNEXPECTED_CHARACTER_ERROR, "Unexpected character.")
 		}
 	}
 }
 
+// Comments starting with /// are doc comments; other line comments are
+// ordinary trivia.
+func (s *SourceScanner) lineComment() {
+	kind := token.LINE_COMMENT
+	if s.peek() == '/' && s.peekNext() != '/' {
+		kind = token.DOC_COMMENT
+	}
+
+	for s.peek() != '\n' && !s.isAtEnd() {
+		s.advance()
+	}
+	s.addTrivia(kind)
+}
+
+// Block comments nest, so that a commented-out region can itself contain
+// block comments. Those starting with /** are doc comments.
+func (s *SourceScanner) blockComment() {
+	kind := token.BLOCK_COMMENT
+	if s.peek() == '*' && s.peekNext() != '/' {
+		kind = token.DOC_COMMENT
+	}
+
+	depth := 1
+	for depth > 0 {
+		if s.isAtEnd() {
+			s.addError(UNTERMINATED_COMMENT_ERROR, "Unterminated block comment.", "Block comments nest, so every '/*' inside needs its own '*/'.")
+			return
+		}
+
+		c := s.advance()
+		if c == '/' && s.match('*') {
+			depth += 1
+		} else if c == '*' && s.match('/') {
+			depth -= 1
+		}
+	}
+	s.addTrivia(kind)
+}
+
 func (s *SourceScanner) string() {
 	for s.peek() != '"' && !s.isAtEnd() {
-		if s.peek() == '\n' {
-			s.line += 1
-		}
 		s.advance()
 	}
 
 	if s.isAtEnd() {
-		s.errors = append(s.errors, NewSourceError(s.line, "", "Unterminated string."))
+		s.addErrorAt(token.Span{Start: s.position(), End: s.position()}, UNTERMINATED_STRING_ERROR, "Unterminated string.", "Add a closing '\"'.")
 		return
 	}
 
@@ -170,24 +292,170 @@ func (s *SourceScanner) string() {
 	s.addToken(token.STRING, value)
 }
 
+// Number literals are decimal, or hexadecimal, binary or octal with a 0x,
+// 0b or 0o prefix. Underscores can separate digits. Decimal literals can
+// have a fractional part and an exponent, and can start with the '.'. A
+// literal with neither is an integer. The n and d suffixes make big integers
+// and decimals.
 func (s *SourceScanner) number() {
-	for isDigit(s.peek()) {
-		s.advance()
+	c, _ := utf8.DecodeRuneInString(s.source[s.start:])
+	if c == '0' {
+		switch s.peek() {
+		case 'x', 'X':
+			s.radixNumber(16, "hexadecimal")
+			return
+		case 'b', 'B':
+			s.radixNumber(2, "binary")
+			return
+		case 'o', 'O':
+			s.radixNumber(8, "octal")
+			return
+		}
 	}
 
-	if s.peek() == '.' && isDigit(s.peekNext()) {
+	fractional := c == '.'
+	s.digits(10)
+	if !fractional && s.peek() == '.' && isDigit(s.peekNext()) {
+		fractional = true
 		s.advance()
+		s.digits(10)
+	}
 
-		for isDigit(s.peek()) {
+	exponent := false
+	if s.peek() == 'e' || s.peek() == 'E' {
+		exponent = true
+		s.advance()
+		if s.peek() == '+' || s.peek() == '-' {
 			s.advance()
 		}
+		if !isDigit(s.peek()) {
+			s.addError(MALFORMED_NUMBER_ERROR, "Expect digits in exponent.")
+			return
+		}
+		s.digits(10)
 	}
 
-	value, err := strconv.ParseFloat(s.source[s.start:s.current], 64)
-	if err != nil {
-		panic("Unexpected numeric conversion error")
+	if !s.checkSeparators(10) {
+		return
+	}
+	text := strings.ReplaceAll(s.source[s.start:s.current], "_", "")
+
+	suffix, ok := s.numberSuffix()
+	if !ok {
+		return
+	}
+	switch suffix {
+	case "n":
+		if fractional {
+			s.addError(MALFORMED_NUMBER_ERROR, "Big integer literal can't have a fractional part.")
+			return
+		}
+		if exponent {
+			s.addError(MALFORMED_NUMBER_ERROR, "Big integer literal can't have an exponent.")
+			return
+		}
+		value, _ := new(big.Int).SetString(text, 10)
+		s.addToken(token.NUMBER, value)
+	case "d":
+		value, err := ParseDecimal(text)
+		if err != nil {
+			s.addError(NUMBER_RANGE_ERROR, "Number literal is out of range.")
+			return
+		}
+		s.addToken(token.NUMBER, value)
+	case "":
+		if fractional || exponent {
+			value, err := strconv.ParseFloat(text, 64)
+			if err != nil {
+				s.addError(NUMBER_RANGE_ERROR, "Number literal is out of range.")
+				return
+			}
+			s.addToken(token.NUMBER, value)
+			return
+		}
+		// Integers too large for int64 become big integers, as if they had
+		// the 'n' suffix
+		if value, err := strconv.ParseInt(text, 10, 64); err == nil {
+			s.addToken(token.NUMBER, value)
+			return
+		}
+		value, _ := new(big.Int).SetString(text, 10)
+		s.addToken(token.NUMBER, value)
 	}
-	s.addToken(token.NUMBER, value)
+}
+
+func (s *SourceScanner) radixNumber(base int, name string) {
+	prefix := s.advance()
+	if !isDigitIn(s.peek(), base) && s.peek() != '_' {
+		s.addError(MALFORMED_NUMBER_ERROR, fmt.Sprintf("Expect digits after '0%c'.", prefix))
+		return
+	}
+	s.digits(base)
+	if !s.checkSeparators(base) {
+		return
+	}
+	text := strings.ReplaceAll(s.source[s.start+2:s.current], "_", "")
+
+	if isDigit(s.peek()) {
+		s.addError(MALFORMED_NUMBER_ERROR, fmt.Sprintf("Invalid digit '%c' in %s literal.", s.peek(), name))
+		return
+	}
+
+	suffix, ok := s.numberSuffix()
+	if !ok {
+		return
+	}
+	switch suffix {
+	case "n":
+		value, _ := new(big.Int).SetString(text, base)
+		s.addToken(token.NUMBER, value)
+	case "":
+		if value, err := strconv.ParseInt(text, base, 64); err == nil {
+			s.addToken(token.NUMBER, value)
+			return
+		}
+		value, _ := new(big.Int).SetString(text, base)
+		s.addToken(token.NUMBER, value)
+	default:
+		s.addError(MALFORMED_NUMBER_ERROR, fmt.Sprintf("Only the 'n' suffix is allowed on %s literals.", name))
+	}
+}
+
+// Consumes digits in the given base along with any underscores among them.
+func (s *SourceScanner) digits(base int) {
+	for isDigitIn(s.peek(), base) || s.peek() == '_' {
+		s.advance()
+	}
+}
+
+// Underscores are only allowed between two digits.
+func (s *SourceScanner) checkSeparators(base int) bool {
+	text := s.source[s.start:s.current]
+	for i := strings.IndexByte(text, '_'); i >= 0; i = strings.IndexByte(text, '_') {
+		if i == 0 || i == len(text)-1 || !isDigitIn(rune(text[i-1]), base) || !isDigitIn(rune(text[i+1]), base) {
+			s.addError(MALFORMED_NUMBER_ERROR, "Underscores in number literals must be between digits.")
+			return false
+		}
+		text = text[i+1:]
+	}
+	return true
+}
+
+// Consumes the letters directly after a number literal, which can only be
+// one of the suffixes the scanner knows about.
+func (s *SourceScanner) numberSuffix() (string, bool) {
+	start := s.current
+	for isAlphaNumeric(s.peek()) {
+		s.advance()
+	}
+
+	suffix := s.source[start:s.current]
+	switch suffix {
+	case "", "n", "d":
+		return suffix, true
+	}
+	s.addError(MALFORMED_NUMBER_ERROR, fmt.Sprintf("Invalid suffix '%s' on number literal.", suffix), "Use 'n' for a big integer or 'd' for a decimal.")
+	return suffix, false
 }
 
 func (s *SourceScanner) identifier() {
@@ -204,14 +472,33 @@ func (s *SourceScanner) identifier() {
 	s.addToken(tokenType, nil)
 }
 
-func isDigit(c byte) bool {
+func isDigit(c rune) bool {
 	return c >= '0' && c <= '9'
 }
 
-func isAlpha(c byte) bool {
-	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c == '_'
+func isHexDigit(c rune) bool {
+	return isDigit(c) || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+func isDigitIn(c rune, base int) bool {
+	switch base {
+	case 16:
+		return isHexDigit(c)
+	case 2:
+		return c == '0' || c == '1'
+	case 8:
+		return c >= '0' && c <= '7'
+	}
+	return isDigit(c)
+}
+
+// Identifiers can use letters from any script.
+func isAlpha(c rune) bool {
+	return unicode.IsLetter(c) || c == '_'
 }
 
-func isAlphaNumeric(c byte) bool {
-	return isDigit(c) || isAlpha(c)
+// After the first character, identifiers can also contain digits and
+// combining marks, which some scripts need to spell ordinary words.
+func isAlphaNumeric(c rune) bool {
+	return isDigit(c) || isAlpha(c) || unicode.IsDigit(c) || unicode.In(c, unicode.Mn, unicode.Mc)
 }
@@ -1,6 +1,8 @@
 package interpreter
 
 import (
+	"math"
+	"math/big"
 	"testing"
 
 	"github.com/DanielleB-R/golox/interpreter/token"
@@ -12,24 +14,32 @@ func scan(source string) ([]*token.Token, error) {
 	return scanner.ScanTokens()
 }
 
+// Places a token on the first line of ASCII source.
+func tokenAt(tok *token.Token, column int) *token.Token {
+	tok.Column = column
+	tok.Offset = column - 1
+	tok.End = token.Position{Offset: tok.Offset + len(tok.Lexeme), Line: tok.Line, Column: column + len(tok.Lexeme)}
+	return tok
+}
+
 func TestBasicTokens(t *testing.T) {
 	cases := map[string]*token.Token{
-		"(":        token.NewToken(token.LEFT_PAREN, "(", nil, 1),
-		")":        token.NewToken(token.RIGHT_PAREN, ")", nil, 1),
-		"+":        token.NewToken(token.PLUS, "+", nil, 1),
-		"!":        token.NewToken(token.BANG, "!", nil, 1),
-		"!=":       token.NewToken(token.BANG_EQUAL, "!=", nil, 1),
-		"=":        token.NewToken(token.EQUAL, "=", nil, 1),
-		" -\t":     token.NewToken(token.MINUS, "-", nil, 1),
-		"\"test\"": token.NewToken(token.STRING, "\"test\"", "test", 1),
-		"1.2":      token.NewToken(token.NUMBER, "1.2", float64(1.2), 1),
-		"test":     token.NewToken(token.IDENTIFIER, "test", nil, 1),
-		"var":      token.NewToken(token.VAR, "var", nil, 1),
+		"(":        tokenAt(token.NewToken(token.LEFT_PAREN, "(", nil, 1), 1),
+		")":        tokenAt(token.NewToken(token.RIGHT_PAREN, ")", nil, 1), 1),
+		"+":        tokenAt(token.NewToken(token.PLUS, "+", nil, 1), 1),
+		"!":        tokenAt(token.NewToken(token.BANG, "!", nil, 1), 1),
+		"!=":       tokenAt(token.NewToken(token.BANG_EQUAL, "!=", nil, 1), 1),
+		"=":        tokenAt(token.NewToken(token.EQUAL, "=", nil, 1), 1),
+		" -\t":     tokenAt(token.NewToken(token.MINUS, "-", nil, 1), 2),
+		"\"test\"": tokenAt(token.NewToken(token.STRING, "\"test\"", "test", 1), 1),
+		"1.2":      tokenAt(token.NewToken(token.NUMBER, "1.2", float64(1.2), 1), 1),
+		"test":     tokenAt(token.NewToken(token.IDENTIFIER, "test", nil, 1), 1),
+		"var":      tokenAt(token.NewToken(token.VAR, "var", nil, 1), 1),
 	}
-	eofToken := token.NewToken(token.EOF, "", nil, 1)
 
 	for input, token := range cases {
 		t.Run(input, func(t *testing.T) {
+			eofToken := tokenAt(newEOF(1), len(input)+1)
 			tokens, err := scan(input)
 			require.NoError(t, err)
 			require.Len(t, tokens, 2)
@@ -39,6 +49,10 @@ func TestBasicTokens(t *testing.T) {
 	}
 }
 
+func newEOF(line int) *token.Token {
+	return token.NewToken(token.EOF, "", nil, line)
+}
+
 func tokenTypes(tokens []*token.Token) []int {
 	types := make([]int, len(tokens))
 	for i, tok := range tokens {
@@ -87,6 +101,8 @@ func TestMultilineString(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, "line1\nline2", tokens[0].Literal)
 	require.Equal(t, 2, tokens[0].Line)
+	require.Equal(t, token.Position{Offset: 0, Line: 1, Column: 1}, tokens[0].Start())
+	require.Equal(t, token.Position{Offset: 13, Line: 2, Column: 7}, tokens[0].End)
 	require.Equal(t, 2, tokens[1].Line, "line count should carry over after multiline string")
 }
 
@@ -106,13 +122,13 @@ func TestUnterminatedStringIsError(t *testing.T) {
 func TestUnterminatedStringAtEOFAfterNewline(t *testing.T) {
 	_, err := scan("\"abc\ndef")
 	require.Error(t, err)
-	require.Contains(t, err.Error(), "[line 2]")
+	require.Contains(t, err.Error(), "[line 2, column 4]")
 }
 
 func TestIntegerWithoutFraction(t *testing.T) {
 	tokens, err := scan("123")
 	require.NoError(t, err)
-	require.Equal(t, float64(123), tokens[0].Literal)
+	require.Equal(t, int64(123), tokens[0].Literal)
 }
 
 func TestTrailingDotIsNotConsumedByNumber(t *testing.T) {
@@ -120,13 +136,15 @@ func TestTrailingDotIsNotConsumedByNumber(t *testing.T) {
 	tokens, err := scan("123.")
 	require.NoError(t, err)
 	require.Equal(t, []int{token.NUMBER, token.DOT, token.EOF}, tokenTypes(tokens))
-	require.Equal(t, float64(123), tokens[0].Literal)
+	require.Equal(t, int64(123), tokens[0].Literal)
 }
 
-func TestLeadingDotIsNotPartOfNumber(t *testing.T) {
-	tokens, err := scan(".5")
+func TestLeadingDotStartsNumber(t *testing.T) {
+	tokens, err := scan(".5 a.b")
 	require.NoError(t, err)
-	require.Equal(t, []int{token.DOT, token.NUMBER, token.EOF}, tokenTypes(tokens))
+	require.Equal(t, []int{token.NUMBER, token.IDENTIFIER, token.DOT, token.IDENTIFIER, token.EOF}, tokenTypes(tokens))
+	require.Equal(t, ".5", tokens[0].Lexeme)
+	require.Equal(t, float64(0.5), tokens[0].Literal)
 }
 
 func TestKeywordIsNotMatchedAsPrefix(t *testing.T) {
@@ -155,7 +173,219 @@ func TestMultipleErrorsAreAllCollected(t *testing.T) {
 	errs, ok := err.(SourceErrors)
 	require.True(t, ok)
 	require.Len(t, errs, 3)
-	require.Contains(t, err.Error(), "[line 1]")
-	require.Contains(t, err.Error(), "[line 2]")
-	require.Contains(t, err.Error(), "[line 3]")
+	require.Contains(t, err.Error(), "[line 1, column 1]")
+	require.Contains(t, err.Error(), "[line 2, column 1]")
+	require.Contains(t, err.Error(), "[line 3, column 1]")
+}
+
+func TestMatchPunctuation(t *testing.T) {
+	tokens, err := scan("[a, _] => ==>")
+	require.NoError(t, err)
+	require.Equal(t, []int{
+		token.LEFT_BRACKET, token.IDENTIFIER, token.COMMA, token.IDENTIFIER, token.RIGHT_BRACKET,
+		token.ARROW, token.EQUAL_EQUAL, token.GREATER, token.EOF,
+	}, tokenTypes(tokens))
+}
+
+func TestEllipsis(t *testing.T) {
+	tokens, err := scan("...rest ..")
+	require.NoError(t, err)
+	require.Equal(t, []int{token.ELLIPSIS, token.IDENTIFIER, token.DOT, token.DOT, token.EOF}, tokenTypes(tokens))
+}
+
+func TestIntegerLiteralTooLargeIsBigInteger(t *testing.T) {
+	tokens, err := scan("9223372036854775807 9223372036854775808")
+	require.NoError(t, err)
+	require.Equal(t, int64(math.MaxInt64), tokens[0].Literal)
+	expected, _ := new(big.Int).SetString("9223372036854775808", 10)
+	require.Equal(t, expected, tokens[1].Literal)
+}
+
+func TestBigIntegerAndDecimalLiterals(t *testing.T) {
+	tokens, err := scan("123456789012345678901234567890n 1.10d 7d")
+	require.NoError(t, err)
+	require.Equal(t, []int{token.NUMBER, token.NUMBER, token.NUMBER, token.EOF}, tokenTypes(tokens))
+	require.Equal(t, "123456789012345678901234567890", stringify(tokens[0].Literal))
+	require.Equal(t, "1.10", stringify(tokens[1].Literal))
+	require.Equal(t, "7", stringify(tokens[2].Literal))
+	require.Equal(t, "1.10d", tokens[1].Lexeme)
+}
+
+func TestFractionalBigIntegerIsError(t *testing.T) {
+	_, err := scan("1.5n")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "Big integer literal can't have a fractional part.")
+}
+
+func TestUnicodeIdentifiersAndColumns(t *testing.T) {
+	tokens, err := scan("var café = \"naïve\"; 名前 = größe;")
+	require.NoError(t, err)
+	require.Equal(t, []int{
+		token.VAR, token.IDENTIFIER, token.EQUAL, token.STRING, token.SEMICOLON,
+		token.IDENTIFIER, token.EQUAL, token.IDENTIFIER, token.SEMICOLON, token.EOF,
+	}, tokenTypes(tokens))
+	require.Equal(t, "café", tokens[1].Lexeme)
+	require.Equal(t, "naïve", tokens[3].Literal)
+	require.Equal(t, "名前", tokens[5].Lexeme)
+
+	columns := []int{}
+	for _, tok := range tokens {
+		columns = append(columns, tok.Column)
+	}
+	require.Equal(t, []int{1, 5, 10, 12, 19, 21, 24, 26, 31, 32}, columns)
+}
+
+func TestCombiningMarksContinueIdentifiers(t *testing.T) {
+	tokens, err := scan("नमस्ते")
+	require.NoError(t, err)
+	require.Equal(t, []int{token.IDENTIFIER, token.EOF}, tokenTypes(tokens))
+	require.Equal(t, "नमस्ते", tokens[0].Lexeme)
+}
+
+func TestUnexpectedNonASCIICharacterReportsRuneColumn(t *testing.T) {
+	_, err := scan("é = 1 €")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "[line 1, column 7] Error: Unexpected character.")
+}
+
+func TestNestedBlockComments(t *testing.T) {
+	tokens, err := scan("1 /* outer /* inner */ still outer */ 2")
+	require.NoError(t, err)
+	require.Equal(t, []int{token.NUMBER, token.NUMBER, token.EOF}, tokenTypes(tokens))
+	require.Len(t, tokens[1].LeadingTrivia, 1)
+	require.Equal(t, &token.Trivia{
+		Kind:   token.BLOCK_COMMENT,
+		Text:   "/* outer /* inner */ still outer */",
+		Line:   1,
+		Column: 3,
+	}, tokens[1].LeadingTrivia[0])
+}
+
+func TestUnterminatedBlockComment(t *testing.T) {
+	_, err := scan("var a;\n/* one /* two */\nvar b;")
+	require.Error(t, err)
+	require.Equal(t, "[line 2, column 1] Error: Unterminated block comment.", err.Error())
+}
+
+func TestCommentsAreKeptAsTrivia(t *testing.T) {
+	tokens, err := scan("// plain\n/// Adds one.\n/// Really.\nfun inc(n) { return n + 1; } /* tail */")
+	require.NoError(t, err)
+
+	fun := tokens[0]
+	require.Equal(t, token.FUN, fun.TokenType)
+	require.Equal(t, []int{token.LINE_COMMENT, token.DOC_COMMENT, token.DOC_COMMENT}, triviaKinds(fun))
+	require.Equal(t, "/// Adds one.", fun.LeadingTrivia[1].Text)
+	require.Equal(t, 2, fun.LeadingTrivia[1].Line)
+	require.Equal(t, "Adds one.\nReally.", fun.DocComment())
+
+	require.Nil(t, tokens[1].LeadingTrivia)
+
+	eof := tokens[len(tokens)-1]
+	require.Equal(t, token.EOF, eof.TokenType)
+	require.Equal(t, []int{token.BLOCK_COMMENT}, triviaKinds(eof))
+}
+
+func TestBlockDocComment(t *testing.T) {
+	tokens, err := scan("/**\n * Makes a point.\n * Two lines.\n */\nclass Point {}\n/**/ ////")
+	require.NoError(t, err)
+	require.Equal(t, []int{token.DOC_COMMENT}, triviaKinds(tokens[0]))
+	require.Equal(t, "Makes a point.\nTwo lines.", tokens[0].DocComment())
+
+	eof := tokens[len(tokens)-1]
+	require.Equal(t, []int{token.BLOCK_COMMENT, token.LINE_COMMENT}, triviaKinds(eof))
+	require.Equal(t, "", eof.DocComment())
+}
+
+func triviaKinds(tok *token.Token) []int {
+	kinds := []int{}
+	for _, trivia := range tok.LeadingTrivia {
+		kinds = append(kinds, trivia.Kind)
+	}
+	return kinds
+}
+
+func TestNumberLiteralSyntax(t *testing.T) {
+	cases := map[string]any{
+		"0xFF":                     int64(255),
+		"0Xff":                     int64(255),
+		"0b1010":                   int64(10),
+		"0o17":                     int64(15),
+		"1_000_000":                int64(1000000),
+		"0xFF_FF":                  int64(65535),
+		"1.5e-3":                   float64(0.0015),
+		"2E3":                      float64(2000),
+		"1_0.2_5e+1_0":             float64(10.25e10),
+		".25":                      float64(0.25),
+		"0x1_0000_0000_0000_0000n": new(big.Int).Lsh(big.NewInt(1), 64),
+		"0x1_0000_0000_0000_0000":  new(big.Int).Lsh(big.NewInt(1), 64),
+		"1_000n":                   big.NewInt(1000),
+		"1.5e2d":                   NewDecimal(big.NewInt(150), 0),
+		"1.25e-1d":                 NewDecimal(big.NewInt(125), 3),
+	}
+
+	for input, expected := range cases {
+		t.Run(input, func(t *testing.T) {
+			tokens, err := scan(input)
+			require.NoError(t, err)
+			require.Equal(t, []int{token.NUMBER, token.EOF}, tokenTypes(tokens))
+			require.Equal(t, input, tokens[0].Lexeme)
+			require.Equal(t, expected, tokens[0].Literal)
+		})
+	}
+}
+
+func TestMalformedNumberLiterals(t *testing.T) {
+	cases := map[string]string{
+		"0x":                      "Expect digits after '0x'.",
+		"0b102":                   "Invalid digit '2' in binary literal.",
+		"0o8":                     "Expect digits after '0o'.",
+		"1__0":                    "Underscores in number literals must be between digits.",
+		"1_":                      "Underscores in number literals must be between digits.",
+		"1_.5":                    "Underscores in number literals must be between digits.",
+		"1e":                      "Expect digits in exponent.",
+		"1e+":                     "Expect digits in exponent.",
+		"12abc":                   "Invalid suffix 'abc' on number literal.",
+		"0x10z":                   "Invalid suffix 'z' on number literal.",
+		"0b1d":                    "Only the 'n' suffix is allowed on binary literals.",
+		"1e400":                   "Number literal is out of range.",
+		"1e999999999d":            "Number literal is out of range.",
+		"1e-999999999d":           "Number literal is out of range.",
+		"1e99999999999999999999d": "Number literal is out of range.",
+		"1e3n":                    "Big integer literal can't have an exponent.",
+	}
+
+	for input, message := range cases {
+		t.Run(input, func(t *testing.T) {
+			_, err := scan(input)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), "[line 1, column 1] Error: "+message)
+		})
+	}
+}
+
+func TestTokenSpans(t *testing.T) {
+	scanner := NewFileScanner("main.lox", "var é = 1;\n  print é;")
+	tokens, err := scanner.ScanTokens()
+	require.NoError(t, err)
+
+	name := tokens[6]
+	require.Equal(t, "é", name.Lexeme)
+	require.Equal(t, token.Span{
+		Start: token.Position{File: "main.lox", Offset: 20, Line: 2, Column: 9},
+		End:   token.Position{File: "main.lox", Offset: 22, Line: 2, Column: 10},
+	}, name.Span())
+	require.Equal(t, "main.lox:2:9", name.Start().String())
+
+	statement := tokens[5].Span().Join(tokens[7].Span())
+	require.Equal(t, "main.lox:2:3-2:11", statement.String())
+	require.Equal(t, statement, tokens[7].Span().Join(tokens[5].Span()))
+	require.Equal(t, statement, token.Span{}.Join(statement))
+}
+
+func TestErrorsIncludeFileName(t *testing.T) {
+	scanner := NewFileScanner("main.lox", "var a;\n@")
+	_, err := scanner.ScanTokens()
+	require.Error(t, err)
+	require.Equal(t, "main.lox: [line 2, column 1] Error: Unexpected character.", err.Error())
+	require.Equal(t, 8, err.(SourceErrors)[0].Span().End.Offset)
 }
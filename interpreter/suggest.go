@@ -0,0 +1,58 @@
+package interpreter
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// Returns " Did you mean 'x'?" for the candidate closest to name, or "" when
+// none of them is close enough to be a likely typo. Ties go to the candidate
+// that sorts first, so the suggestion doesn't depend on map order.
+func suggestion(name string, candidates []string) string {
+	limit := max(1, utf8.RuneCountInString(name)/3)
+	best, bestDistance := "", limit+1
+	for _, candidate := range candidates {
+		if candidate == name {
+			continue
+		}
+		distance := editDistance(name, candidate)
+		if distance < bestDistance || (distance == bestDistance && candidate < best) {
+			best, bestDistance = candidate, distance
+		}
+	}
+
+	if best == "" {
+		return ""
+	}
+	return fmt.Sprintf(" Did you mean '%s'?", best)
+}
+
+// The number of single rune insertions, deletions, substitutions and swaps
+// of adjacent runes needed to turn one string into the other.
+func editDistance(a string, b string) int {
+	left, right := []rune(a), []rune(b)
+
+	// Only three rows are needed: the current one and the two before it
+	previous2 := make([]int, len(right)+1)
+	previous := make([]int, len(right)+1)
+	current := make([]int, len(right)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(left); i++ {
+		current[0] = i
+		for j := 1; j <= len(right); j++ {
+			cost := 1
+			if left[i-1] == right[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+			if i > 1 && j > 1 && left[i-1] == right[j-2] && left[i-2] == right[j-1] {
+				current[j] = min(current[j], previous2[j-2]+1)
+			}
+		}
+		previous2, previous, current = previous, current, previous2
+	}
+	return previous[len(right)]
+}
@@ -0,0 +1,33 @@
+package interpreter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEditDistance(t *testing.T) {
+	cases := []struct {
+		a, b     string
+		distance int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"kitten", "sitting", 3},
+		{"lenght", "length", 1},
+		{"prnt", "print", 1},
+		{"größe", "grösse", 2},
+	}
+
+	for _, c := range cases {
+		require.Equal(t, c.distance, editDistance(c.a, c.b), "%s -> %s", c.a, c.b)
+		require.Equal(t, c.distance, editDistance(c.b, c.a), "%s -> %s", c.b, c.a)
+	}
+}
+
+func TestSuggestion(t *testing.T) {
+	require.Equal(t, " Did you mean 'length'?", suggestion("lenght", []string{"push", "length", "get"}))
+	require.Equal(t, " Did you mean 'bar'?", suggestion("baz", []string{"car", "bar"}))
+	require.Equal(t, "", suggestion("x", []string{"abc"}))
+	require.Equal(t, "", suggestion("name", []string{"name"}))
+}
@@ -0,0 +1,56 @@
+package token
+
+import "fmt"
+
+// A Position is a place in a source file. Offset is in bytes from the start
+// of the file, while Line and Column count from 1 and Column counts runes.
+type Position struct {
+	File   string
+	Offset int
+	Line   int
+	Column int
+}
+
+func (p Position) String() string {
+	location := fmt.Sprintf("%d:%d", p.Line, p.Column)
+	if p.File == "" {
+		return location
+	}
+	return p.File + ":" + location
+}
+
+// A Span covers the source from Start up to but not including End.
+type Span struct {
+	Start Position
+	End   Position
+}
+
+// The zero Span is used for code with no source, such as the parts of a
+// desugared statement.
+func (s Span) IsValid() bool {
+	return s.Start.Line > 0
+}
+
+// Returns the smallest span covering both spans, which should be in the same
+// file. Joining with an invalid span has no effect.
+func (s Span) Join(other Span) Span {
+	if !s.IsValid() {
+		return other
+	}
+	if !other.IsValid() {
+		return s
+	}
+
+	joined := s
+	if other.Start.Offset < joined.Start.Offset {
+		joined.Start = other.Start
+	}
+	if other.End.Offset > joined.End.Offset {
+		joined.End = other.End
+	}
+	return joined
+}
+
+func (s Span) String() string {
+	return fmt.Sprintf("%s-%d:%d", s.Start, s.End.Line, s.End.Column)
+}
@@ -1,6 +1,9 @@
 package token
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 const (
 	// Single-char tokens
@@ -8,6 +11,9 @@ const (
 	RIGHT_PAREN
 	LEFT_BRACE
 	RIGHT_BRACE
+	LEFT_BRACKET
+	RIGHT_BRACKET
+	COLON
 	COMMA
 	DOT
 	MINUS
@@ -16,9 +22,11 @@ const (
 	SLASH
 	STAR
 
-	// One or two char tokens
+	// One or more char tokens
+	ARROW
 	BANG
 	BANG_EQUAL
+	ELLIPSIS
 	EQUAL
 	EQUAL_EQUAL
 	GREATER
@@ -33,12 +41,16 @@ const (
 
 	// Keywords
 	AND
+	BREAK
+	CASE
 	CLASS
+	CONTINUE
 	ELSE
 	FALSE
 	FUN
 	FOR
 	IF
+	MATCH
 	NIL
 	OR
 	PRINT
@@ -53,29 +65,60 @@ const (
 )
 
 var Keywords = map[string]int{
-	"and":    AND,
-	"class":  CLASS,
-	"else":   ELSE,
-	"false":  FALSE,
-	"for":    FOR,
-	"fun":    FUN,
-	"if":     IF,
-	"nil":    NIL,
-	"or":     OR,
-	"print":  PRINT,
-	"return": RETURN,
-	"super":  SUPER,
-	"this":   THIS,
-	"true":   TRUE,
-	"var":    VAR,
-	"while":  WHILE,
+	"and":      AND,
+	"break":    BREAK,
+	"case":     CASE,
+	"class":    CLASS,
+	"continue": CONTINUE,
+	"else":     ELSE,
+	"false":    FALSE,
+	"for":      FOR,
+	"fun":      FUN,
+	"if":       IF,
+	"match":    MATCH,
+	"nil":      NIL,
+	"or":       OR,
+	"print":    PRINT,
+	"return":   RETURN,
+	"super":    SUPER,
+	"this":     THIS,
+	"true":     TRUE,
+	"var":      VAR,
+	"while":    WHILE,
+}
+
+// Kinds of trivia
+const (
+	LINE_COMMENT = iota
+	BLOCK_COMMENT
+	DOC_COMMENT
+)
+
+// Trivia is source text that doesn't affect the program, kept so that tools
+// can reproduce it. Text includes the comment delimiters.
+type Trivia struct {
+	Kind   int
+	Text   string
+	Line   int
+	Column int
 }
 
+// Line is the line the token ends on, which is later than the line it starts
+// on for a string that spans lines. Column and Offset are where the token
+// starts, and End is just past its last character. Column counts runes from 1
+// at the start of the line. File is empty for source that didn't come from a
+// file. LeadingTrivia holds the comments between the previous token and this
+// one.
 type Token struct {
-	TokenType int
-	Lexeme    string
-	Literal   any
-	Line      int
+	TokenType     int
+	Lexeme        string
+	Literal       any
+	File          string
+	Offset        int
+	Line          int
+	Column        int
+	End           Position
+	LeadingTrivia []*Trivia
 }
 
 func NewToken(tokenType int, lexeme string, literal any, line int) *Token {
@@ -87,6 +130,41 @@ func NewToken(tokenType int, lexeme string, literal any, line int) *Token {
 	}
 }
 
+// A token can only span lines if its lexeme does, so the line it starts on is
+// found from the line breaks in the lexeme.
+func (t *Token) Start() Position {
+	line := t.Line - strings.Count(t.Lexeme, "\n")
+	return Position{File: t.File, Offset: t.Offset, Line: line, Column: t.Column}
+}
+
+func (t *Token) Span() Span {
+	return Span{Start: t.Start(), End: t.End}
+}
+
 func (t *Token) String() string {
 	return fmt.Sprintf("%d %s %v", t.TokenType, t.Lexeme, t.Literal)
 }
+
+// Returns the text of the doc comments leading up to this token, with the
+// comment markers removed.
+func (t *Token) DocComment() string {
+	lines := []string{}
+	for _, trivia := range t.LeadingTrivia {
+		if trivia.Kind != DOC_COMMENT {
+			continue
+		}
+
+		if text, ok := strings.CutPrefix(trivia.Text, "///"); ok {
+			lines = append(lines, strings.TrimPrefix(text, " "))
+			continue
+		}
+
+		text := strings.TrimSuffix(strings.TrimPrefix(trivia.Text, "/**"), "*/")
+		for _, line := range strings.Split(strings.TrimSpace(text), "\n") {
+			line = strings.TrimSpace(line)
+			line = strings.TrimPrefix(strings.TrimPrefix(line, "*"), " ")
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
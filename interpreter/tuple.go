@@ -0,0 +1,34 @@
+package interpreter
+
+import (
+	"fmt"
+)
+
+var (
+	_ fmt.Stringer = (*LoxTuple)(nil)
+)
+
+// Tuples are immutable and compare equal when their elements do.
+type LoxTuple struct {
+	elements []any
+}
+
+func NewLoxTuple(elements []any) *LoxTuple {
+	return &LoxTuple{elements: elements}
+}
+
+func (l *LoxTuple) String() string {
+	return formatElements(l, map[any]bool{})
+}
+
+func (l *LoxTuple) Equal(other *LoxTuple) bool {
+	if len(l.elements) != len(other.elements) {
+		return false
+	}
+	for i, element := range l.elements {
+		if !isEqual(element, other.elements[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,117 @@
+package interpreter
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/DanielleB-R/golox/interpreter/token"
+)
+
+var _ Diagnostic = (*Warning)(nil)
+
+type WarningCategory int
+
+const (
+	UNUSED_VARIABLE WarningCategory = iota
+	SHADOWED_VARIABLE
+	UNREACHABLE_CODE
+	SELF_COMPARISON
+)
+
+// The names used to switch categories on and off, in category order.
+var warningCategoryNames = []string{"unused", "shadow", "unreachable", "self-compare"}
+
+func (c WarningCategory) String() string {
+	return warningCategoryNames[c]
+}
+
+func (c WarningCategory) code() string {
+	return fmt.Sprintf("W03%02d", int(c)+1)
+}
+
+// Warnings point out code that is probably a mistake but can still run.
+type Warning struct {
+	category WarningCategory
+	span     token.Span
+	message  string
+}
+
+func (w *Warning) Category() WarningCategory {
+	return w.category
+}
+
+func (w *Warning) Span() token.Span {
+	return w.span
+}
+
+func (w *Warning) String() string {
+	return fmt.Sprintf("%sWarning line %d: %s [%s]", filePrefix(w.span.Start.File), w.span.Start.Line, w.message, w.category)
+}
+
+func (*Warning) Phase() Phase {
+	return RESOLVE_PHASE
+}
+
+func (*Warning) Severity() Severity {
+	return WARNING_SEVERITY
+}
+
+func (w *Warning) Code() string {
+	return w.category.code()
+}
+
+func (w *Warning) Message() string {
+	return w.message
+}
+
+func (*Warning) Notes() []string {
+	return nil
+}
+
+func sortWarnings(warnings []*Warning) {
+	slices.SortStableFunc(warnings, func(a, b *Warning) int {
+		return cmp.Or(cmp.Compare(a.span.Start.Line, b.span.Start.Line), cmp.Compare(a.span.Start.Column, b.span.Start.Column))
+	})
+}
+
+// The set of warning categories that are reported.
+type WarningSet map[WarningCategory]bool
+
+func AllWarnings() WarningSet {
+	warnings := WarningSet{}
+	for category := range warningCategoryNames {
+		warnings[WarningCategory(category)] = true
+	}
+	return warnings
+}
+
+// Applies a comma-separated list of changes to the set, in order. Each one is
+// "all", "none", a category name to turn it on, or a category name prefixed
+// with "no-" to turn it off, as in "all,no-shadow".
+func (s WarningSet) Apply(spec string) error {
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		switch item {
+		case "":
+			continue
+		case "all":
+			for category := range warningCategoryNames {
+				s[WarningCategory(category)] = true
+			}
+			continue
+		case "none":
+			clear(s)
+			continue
+		}
+
+		name, disable := strings.CutPrefix(item, "no-")
+		category := slices.Index(warningCategoryNames, name)
+		if category < 0 {
+			return fmt.Errorf("unknown warning category '%s'; expected one of %s", name, strings.Join(warningCategoryNames, ", "))
+		}
+		s[WarningCategory(category)] = !disable
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
 
@@ -8,13 +9,31 @@ import (
 )
 
 func main() {
-	if len(os.Args) > 2 {
-		fmt.Fprintln(os.Stderr, "Usage: golox [script]")
+	options := interpreter.DefaultRunOptions()
+	flag.Func("warn", "warnings to report, as a comma-separated list of all, none, unused, shadow, unreachable and self-compare, each of which can be prefixed with no-", options.Warnings.Apply)
+	flag.Func("diagnostics", "format of errors and warnings, text or json", func(format string) error {
+		switch format {
+		case "text":
+			options.JSONDiagnostics = false
+		case "json":
+			options.JSONDiagnostics = true
+		default:
+			return fmt.Errorf("unknown format '%s'", format)
+		}
+		return nil
+	})
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: golox [flags] [script]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() > 1 {
+		flag.Usage()
 		os.Exit(64)
-	} else if len(os.Args) == 2 {
-		interpreter.RunFile(os.Args[1])
+	} else if flag.NArg() == 1 {
+		interpreter.RunFile(flag.Arg(0), options)
 	} else {
-		interpreter.RunPrompt()
+		interpreter.RunPrompt(options)
 	}
-
 }